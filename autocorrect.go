@@ -0,0 +1,91 @@
+package mydocx
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Typographic characters that Word's autocorrect puts in place of template syntax, with the character they replace.
+var autocorrected = map[rune]string{
+	'“': `"`, '”': `"`, '„': `"`, '«': `"`, '»': `"`,
+	'‘': "'", '’': "'",
+	'–': "-", '—': "-",
+	'\u00a0': " ", '\u202f': " ", // non-breaking spaces, inserted by the french autocorrect before : and within « »
+}
+
+// Quotes that close a string literal opened by a typographic quote.
+var closingQuotes = map[rune]bool{'“': true, '”': true, '»': true, '"': true}
+
+// Undo the changes of Word's autocorrect within the actions of a template text, before parsing it :
+// typographic quotes become straight quotes, dashes become minus signs, and non-breaking spaces become spaces.
+// The text outside of the actions, and the content of string literals, are left untouched.
+// For instance, {{printf “%s–%s” .A .B}} becomes {{printf "%s–%s" .A .B}}.
+func normalizeActions(text string) string {
+	actions := scanActions(text)
+	if len(actions) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	pos := 0
+	for _, a := range actions {
+		sb.WriteString(text[pos:a.start])
+		normalizeAction(&sb, text[a.start:a.end])
+		pos = a.end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// Write the normalized text of an action.
+func normalizeAction(sb *strings.Builder, action string) {
+	for i := 0; i < len(action); {
+		r, size := utf8.DecodeRuneInString(action[i:])
+		switch {
+		case r == '"' || r == '`':
+			end := literalEnd(action, i)
+			sb.WriteString(action[i:end])
+			i = end
+			continue
+		case autocorrected[r] == `"`:
+			end, content := typographicLiteral(action, i+size)
+			if end >= 0 {
+				sb.WriteString(`"` + content + `"`)
+				i = end
+				continue
+			}
+		}
+		if s, ok := autocorrected[r]; ok {
+			sb.WriteString(s)
+		} else {
+			sb.WriteString(action[i : i+size])
+		}
+		i += size
+	}
+}
+
+// Get the position just after the straight string literal starting at pos, or the end of the text if it is not terminated.
+func literalEnd(text string, pos int) int {
+	q := text[pos]
+	for i := pos + 1; i < len(text); i++ {
+		switch {
+		case text[i] == q:
+			return i + 1
+		case text[i] == '\\' && q == '"':
+			i++
+		}
+	}
+	return len(text)
+}
+
+// Get the position just after the string literal whose content starts at pos, opened by a typographic quote,
+// and its content, without leading and trailing non-breaking spaces (Word puts them within « »). The position is -1 if the literal is not closed.
+func typographicLiteral(text string, pos int) (int, string) {
+	for i := pos; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if closingQuotes[r] {
+			return i + size, strings.Trim(text[pos:i], "\u00a0\u202f")
+		}
+		i += size
+	}
+	return -1, ""
+}
@@ -0,0 +1,47 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeActions(t *testing.T) {
+	for _, tc := range []struct{ text, want string }{
+		{`“Quoted” text – {{.Name}}`, `“Quoted” text – {{.Name}}`}, // nothing to change outside actions
+		{`Dear {{printf “%s’s” .Name}} !`, `Dear {{printf "%s’s" .Name}} !`},
+		{`{{printf „%d“ .N}} {{printf ‘x’}}`, `{{printf "%d" .N}} {{printf 'x'}}`},
+		{"{{printf « %s » .Name}}", `{{printf "%s" .Name}}`},
+		{"{{$x := .Name}}{{– $x –}}", `{{$x := .Name}}{{- $x -}}`},
+		{`{{printf "“%s” – %s" .A .B}} {{printf ` + "`–`" + `}}`, `{{printf "“%s” – %s" .A .B}} {{printf ` + "`–`" + `}}`}, // straight literals are kept
+		{`{{printf “%s" .Name}}`, `{{printf "%s" .Name}}`},                                                                 // mixed quotes
+		{`{{printf “%s .Name}}`, `{{printf "%s .Name}}`},                                                                   // not closed : left to the parser
+		{`{{.Total`, `{{.Total`},
+	} {
+		if got := normalizeActions(tc.text); got != tc.want {
+			t.Errorf("normalizeActions(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+
+	replace := NewTplReplacer(map[string]any{"Name": "Ann", "N": 3})
+	got := replace("", "Dear « {{printf “%s – %d” .Name .N}} »")
+	if len(got) != 1 || got[0] != "Dear « Ann – 3 »" {
+		t.Errorf("unexpected result %q", got)
+	}
+
+	// blocks spanning paragraphs
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("{{if eq .Name “Ann”}}") + testPara("Welcome {{.Name}}") + testPara("{{end}}")),
+	})
+	out, err := ExecuteTemplateBytes(docx, map[string]any{"Name": "Ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pp["word/document.xml"], "|") != "Welcome Ann" {
+		t.Errorf("unexpected result %q", pp)
+	}
+}
@@ -0,0 +1,288 @@
+package mydocx
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// A BookmarkReplacer provides the new text of a bookmark, given its name and its current text.
+// Paragraphs spanned by the bookmark are separated by \n in the original text.
+// Returning the original text leaves the bookmark unchanged.
+// Lines of the replaced text are separated by line breaks, within the first paragraph of the bookmark.
+type BookmarkReplacer func(bookmark string, original string) (replaced string)
+
+// NewBookmarkReplacer creates a BookmarkReplacer that sets the text of the bookmarks found in values.
+// Other bookmarks are left unchanged.
+func NewBookmarkReplacer(values map[string]string) BookmarkReplacer {
+	return func(bookmark string, original string) string {
+		if v, ok := values[bookmark]; ok {
+			return v
+		}
+		return original
+	}
+}
+
+// Extract the text of all the bookmarks of the docx file.
+// Returns a map from the bookmark name to the text between its start and its end, as if all changes were accepted.
+// Paragraphs spanned by a bookmark are separated by \n. Empty (insertion point) bookmarks have an empty text.
+func ExtractBookmarks(sourceFilePath string, opts ...Option) (map[string]string, error) {
+	return fromFile(sourceFilePath, ExtractBookmarksBytes, opts)
+}
+
+// Same as ExtractBookmarks, but takes a byte array as input.
+func ExtractBookmarksBytes(sourceBytes []byte, opts ...Option) (map[string]string, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	res := make(map[string]string)
+	for _, name := range pkg.names(conf.parts) {
+		content, err := pkg.read(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		order := newDocOrder(root)
+		for _, b := range findBookmarks(root) {
+			if _, ok := res[b.name]; !ok {
+				res[b.name] = order.bookmarkText(b)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Modify the text of the bookmarks of the sourceFile, by applying the BookmarkReplacer.
+// The replaced text is saved in the first run of the bookmark, other runs within the bookmark are emptied,
+// and paragraphs entirely within the bookmark are removed. The bookmark itself is preserved, and ends just after the replaced text.
+// Empty (insertion point) bookmarks receive a new run, formatted as the following (or preceding) run of the paragraph.
+// If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
+func ModifyBookmarks(sourceFilePath string, replace BookmarkReplacer, targetFilePath string, opts ...Option) error {
+	return modifyFile(sourceFilePath, targetFilePath, "modify bookmarks", func(in []byte) ([]byte, error) {
+		return ModifyBookmarksBytes(in, replace, opts...)
+	})
+}
+
+// Same as ModifyBookmarks, but takes a byte array as input and returns the modified docx as a byte array.
+func ModifyBookmarksBytes(sourceBytes []byte, replace BookmarkReplacer, opts ...Option) ([]byte, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input bytes: %v", err)
+	}
+	if replace == nil {
+		replace = func(_, s string) string { return s }
+	}
+	return pkg.rewrite(conf.parts, func(_ string, root *xnode) error {
+		order := newDocOrder(root) // updated as bookmarks are replaced
+		for _, b := range findBookmarks(root) {
+			original := order.bookmarkText(b)
+			replaced := replace(b.name, original)
+			if replaced == original {
+				continue
+			}
+			if VERBOSE {
+				fmt.Printf("Replacing bookmark %q : %q -> %q\n", b.name, original, replaced)
+			}
+			order.setBookmarkText(b, replaced)
+		}
+		return nil
+	})
+}
+
+// bookmark is a bookmark found in a container tree.
+type bookmark struct {
+	name       string
+	start, end *xnode // w:bookmarkStart and w:bookmarkEnd elements
+}
+
+// Find the bookmarks of the tree, in the document order of their start.
+// Bookmarks with no end are ignored.
+func findBookmarks(root *xnode) (res []bookmark) {
+	ends := make(map[string]*xnode)
+	for _, e := range root.collect("bookmarkEnd") {
+		id, _ := e.attrValue(NAMESPACE, "id")
+		ends[id] = e
+	}
+	for _, s := range root.collect("bookmarkStart") {
+		id, _ := s.attrValue(NAMESPACE, "id")
+		name, _ := s.attrValue(NAMESPACE, "name")
+		if e, ok := ends[id]; ok {
+			res = append(res, bookmark{name: name, start: s, end: e})
+		}
+	}
+	return res
+}
+
+// docOrder records the position of each node of a tree, in document order.
+// Alternate content fallbacks are ignored.
+// Nodes added to the tree share the position of the node they follow (see place) :
+// the bookmarks, whose starts and ends have distinct positions, see them as that node.
+type docOrder struct {
+	pos   map[*xnode]int // position of the start of each node
+	last  map[*xnode]int // position of the last descendant of each node
+	paras []*xnode       // paragraphs of the tree, in document order, without the removed ones
+}
+
+// Compute the document order of the tree nodes.
+func newDocOrder(root *xnode) *docOrder {
+	o := &docOrder{pos: make(map[*xnode]int), last: make(map[*xnode]int), paras: root.paragraphs()}
+	var visit func(n *xnode)
+	visit = func(n *xnode) {
+		o.pos[n] = len(o.pos)
+		for _, c := range n.children {
+			if !c.isFallback() {
+				visit(c)
+			}
+		}
+		o.last[n] = len(o.pos) - 1
+	}
+	visit(root)
+	return o
+}
+
+// Check if the node is located strictly between from and to, in document order.
+func (o *docOrder) between(n, from, to *xnode) bool {
+	p, ok := o.pos[n]
+	return ok && p > o.pos[from] && p < o.pos[to]
+}
+
+// Give the nodes of the subtree added to the tree the position of the node they follow.
+func (o *docOrder) place(n *xnode, after *xnode) {
+	n.walk(func(c *xnode) bool {
+		if _, ok := o.pos[c]; !ok {
+			o.pos[c], o.last[c] = o.pos[after], o.pos[after]
+		}
+		return true
+	})
+}
+
+// Remove a paragraph from the tree, with the paragraphs it contains.
+func (o *docOrder) remove(p *xnode) {
+	i := o.search(o.pos[p])
+	j := o.search(o.last[p] + 1)
+	o.paras = slices.Delete(o.paras, i, j)
+	p.remove()
+}
+
+// Get the index of the first paragraph at or after a position.
+func (o *docOrder) search(pos int) int {
+	return sort.Search(len(o.paras), func(i int) bool { return o.pos[o.paras[i]] >= pos })
+}
+
+// Get the text elements within the bookmark, grouped by paragraph, in document order.
+// Only the paragraphs from the outermost paragraph of the start, that may hold text after it, to the end are checked.
+func (o *docOrder) bookmarkTexts(b bookmark) (paras []*xnode, texts [][]*xnode) {
+	from := o.pos[b.start]
+	for p := b.start.ancestor("p"); p != nil; p = p.ancestor("p") {
+		from = o.pos[p]
+	}
+	for _, p := range o.paras[o.search(from):] {
+		if o.pos[p] > o.pos[b.end] {
+			break
+		}
+		var tt []*xnode
+		for _, t := range paragraphTexts(p, acceptedView) {
+			if o.between(t, b.start, b.end) {
+				tt = append(tt, t)
+			}
+		}
+		if len(tt) > 0 || o.between(p, b.start, b.end) {
+			paras, texts = append(paras, p), append(texts, tt)
+		}
+	}
+	return paras, texts
+}
+
+// Get the text of a bookmark, paragraphs are separated by \n.
+func (o *docOrder) bookmarkText(b bookmark) string {
+	_, texts := o.bookmarkTexts(b)
+	lines := make([]string, len(texts))
+	for i, tt := range texts {
+		for _, t := range tt {
+			lines[i] += t.textContent()
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Replace the text of a bookmark.
+// The end of a bookmark spanning several paragraphs is moved just after the replaced text, in the first paragraph,
+// so that the bookmark reads back as the replaced text.
+func (o *docOrder) setBookmarkText(b bookmark, text string) {
+	paras, texts := o.bookmarkTexts(b)
+	var first *xnode // text element receiving the new text
+	for i, p := range paras {
+		inside := o.between(p, b.start, b.end) && o.last[p] < o.pos[b.end]
+		if inside && first != nil {
+			o.remove(p) // paragraph entirely replaced
+			continue
+		}
+		for _, t := range texts[i] {
+			if first == nil {
+				first = t
+			} else {
+				t.setText("")
+			}
+		}
+	}
+	if first == nil { // empty bookmark, create a run to hold the text
+		first = insertBookmarkRun(b)
+		if first == nil {
+			return
+		}
+		o.place(first.parent, b.start)
+	}
+	setTextLines(first, text)
+	o.place(first.parent, first)
+	if r := first.ancestor("r"); r != nil && b.end.ancestor("p") != first.ancestor("p") {
+		b.end.remove()
+		r.insertAfter(b.end)
+	}
+}
+
+// Create an empty run just after the start of an empty bookmark, and return its text element.
+// The run copies the properties of the next run of the paragraph, or else the previous one.
+// Returns nil if the bookmark start is not within a paragraph.
+func insertBookmarkRun(b bookmark) *xnode {
+	p := b.start.ancestor("p")
+	if p == nil {
+		return nil
+	}
+	var prev, next *xnode
+	afterStart := false
+	p.walk(func(n *xnode) bool {
+		switch {
+		case n == b.start:
+			afterStart = true
+		case n.is("r") && n.ancestor("p") == p:
+			if !afterStart {
+				prev = n
+			} else if next == nil {
+				next = n
+			}
+			return false
+		}
+		return true
+	})
+	model := next
+	if model == nil {
+		model = prev
+	}
+	props := ""
+	if model != nil && model.child("rPr") != nil {
+		props = string(model.child("rPr").bytes())
+	}
+	run, err := parseFragment(`<w:r>` + props + `<w:t xml:space="preserve"></w:t></w:r>`)
+	if err != nil {
+		panic("invalid run fragment : " + err.Error()) // should never happen
+	}
+	b.start.insertAfter(run...)
+	return run[0].child("t")
+}
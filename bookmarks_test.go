@@ -0,0 +1,100 @@
+package mydocx
+
+import (
+	"testing"
+)
+
+// bookmarks : within a paragraph across runs, spanning three paragraphs, and an empty insertion point
+const testBookmarks = `<w:p><w:r><w:t xml:space="preserve">Dear </w:t></w:r><w:bookmarkStart w:id="0" w:name="name"/><w:r><w:t>Jo</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>hn</w:t></w:r><w:bookmarkEnd w:id="0"/><w:r><w:t>,</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t xml:space="preserve">Intro </w:t></w:r><w:bookmarkStart w:id="1" w:name="clause"/><w:r><w:t>first</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t>second</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t>third</w:t></w:r><w:bookmarkEnd w:id="1"/><w:r><w:t xml:space="preserve"> end</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t xml:space="preserve">Signed on </w:t></w:r><w:bookmarkStart w:id="2" w:name="date"/><w:bookmarkEnd w:id="2"/><w:r><w:rPr><w:i/></w:rPr><w:t>.</w:t></w:r></w:p>`
+
+func TestBookmarks(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testBookmarks),
+	})
+
+	bm, err := ExtractBookmarksBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"name": "John", "clause": "first\nsecond\nthird", "date": ""}
+	if len(bm) != len(want) {
+		t.Fatalf("got %q, want %q", bm, want)
+	}
+	for k, v := range want {
+		if bm[k] != v {
+			t.Errorf("bookmark %s : got %q, want %q", k, bm[k], v)
+		}
+	}
+
+	out, err := ModifyBookmarksBytes(docx, NewBookmarkReplacer(map[string]string{
+		"name":   "Jane",
+		"clause": "new clause",
+		"date":   "today",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm, err = ExtractBookmarksBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{"name": "Jane", "clause": "new clause", "date": "today"}
+	for k, v := range want {
+		if bm[k] != v {
+			t.Errorf("bookmark %s : got %q, want %q", k, bm[k], v)
+		}
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paras := pp["word/document.xml"]
+	wantParas := []string{"Dear Jane,", "Intro new clause", " end", "Signed on today."}
+	if len(paras) != len(wantParas) {
+		t.Fatalf("got %q, want %q", paras, wantParas)
+	}
+	for i := range paras {
+		if paras[i] != wantParas[i] {
+			t.Errorf("paragraph %d : got %q, want %q", i, paras[i], wantParas[i])
+		}
+	}
+
+	// overlapping bookmarks see the text replaced so far : "b" starts within "a", and holds the empty "c".
+	// The text of "b" is saved in its first text element, emptied by "a", the new line of "a" is not within "b".
+	overlapping := makeDocx(t, map[string]string{
+		contentTypesName: testContentTypes,
+		"word/document.xml": testDocument(`<w:p><w:bookmarkStart w:id="3" w:name="a"/><w:r><w:t>x</w:t></w:r><w:bookmarkStart w:id="4" w:name="b"/>` +
+			`<w:r><w:t>y</w:t></w:r><w:bookmarkEnd w:id="3"/><w:r><w:t>z</w:t></w:r><w:bookmarkStart w:id="5" w:name="c"/><w:bookmarkEnd w:id="5"/><w:bookmarkEnd w:id="4"/></w:p>`),
+	})
+	originals := make(map[string]string)
+	out, err = ModifyBookmarksBytes(overlapping, func(name, original string) string {
+		originals[name] = original
+		return map[string]string{"a": "A1\nA2", "b": original + "B", "c": "C"}[name]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if originals["a"] != "xy" || originals["b"] != "z" || originals["c"] != "" {
+		t.Errorf("unexpected original texts : %q", originals)
+	}
+	bm, _ = ExtractBookmarksBytes(out)
+	if bm["a"] != "A1A2zB" || bm["b"] != "zBC" || bm["c"] != "C" {
+		t.Errorf("unexpected bookmarks : %q", bm)
+	}
+
+	// unchanged bookmarks leave the document untouched
+	out, err = ModifyBookmarksBytes(docx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm, _ = ExtractBookmarksBytes(out)
+	if bm["clause"] != "first\nsecond\nthird" {
+		t.Errorf("unexpected bookmark text : %q", bm["clause"])
+	}
+}
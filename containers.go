@@ -0,0 +1,201 @@
+package mydocx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Container describes a container (part) of a docx document, and its role in the document.
+type Container struct {
+	// Zip file name of the container, eg : word/header2.xml
+	Name string
+	// Kind of container : PartBody, PartHeaders, PartFooters, ...
+	Kind Parts
+	// For headers and footers, index of the first section (starting at 0) that references it.
+	// Set to -1 for other containers, and for headers or footers that no section references.
+	Section int
+	// For headers and footers, type of the reference : "default", "first" (first page) or "even" (even pages).
+	// Empty for other containers.
+	Type string
+	// For headers and footers, indexes of all the sections using it : the sections that reference it,
+	// and the following sections that inherit it, since they do not reference another header or footer of the same type.
+	Sections []int
+	// Text contained in the paragraphs of the container, as if all changes had been accepted (see ExtractText).
+	Paragraphs []string
+}
+
+// Header and footer reference types, in the order they are listed within a section.
+var referenceTypes = map[string]int{"first": 0, "default": 1, "even": 2}
+
+// Kinds of containers, in reading order.
+var readingOrder = []Parts{PartHeaders, PartBody, PartFooters, PartFootnotes, PartEndnotes, PartComments, PartGlossary}
+
+// Extract text content from docx file, container by container, in reading order.
+// The headers are listed first, ordered by the first section using them, then by type (first page, default, even pages),
+// then the main body, that holds all the sections, then the footers in the same order, then footnotes, endnotes, comments and glossary.
+// Headers and footers are reported with the sections and the type of the references found in the main document.
+// This function is thread-safe.
+// Options can prefix paragraphs with their list labels (see WithNumbering), or extract field instructions instead of field results (see WithFieldCodes).
+func ExtractContainers(sourceFilePath string, opts ...Option) ([]Container, error) {
+	if VERBOSE {
+		fmt.Printf("Extracting containers from %s\n", sourceFilePath)
+	}
+	return fromFile(sourceFilePath, ExtractContainersBytes, opts)
+}
+
+// Same as ExtractContainers, but takes a byte array as input.
+func ExtractContainersBytes(sourceBytes []byte, opts ...Option) ([]Container, error) {
+
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	res, err := pkg.containers(conf.parts)
+	if err != nil {
+		return nil, err
+	}
+	nb, err := conf.numberingOf(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load numbering: %v", err)
+	}
+	for i := range res {
+		content, err := pkg.read(res[i].Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", res[i].Name, err)
+		}
+		res[i].Paragraphs, err = extractParagraphsView(content, acceptedView, nb, conf.fieldCodes)
+		if err != nil {
+			return res, fmt.Errorf("failed to extract text from %s : %v", res[i].Name, err)
+		}
+	}
+	return res, nil
+}
+
+// List the selected containers of the package in reading order, with their role.
+// Paragraphs are not extracted.
+func (pkg *docxPackage) containers(parts Parts) ([]Container, error) {
+
+	var res []Container
+	for _, name := range pkg.names(parts) {
+		res = append(res, Container{Name: name, Kind: pkg.partKind(name), Section: -1})
+	}
+
+	// resolve header and footer references from the main document sections
+	for _, main := range pkg.names(PartBody) {
+		content, err := pkg.read(main)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", main, err)
+		}
+		refs, sections, err := sectionReferences(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sections from %s: %v", main, err)
+		}
+		rels, err := pkg.rels(main)
+		if err != nil {
+			return nil, err
+		}
+		// the references in use, by kind and type : inherited from the previous section unless the section overrides them
+		current := make(map[string]string)
+		for section := 0; section < sections; section++ {
+			for _, ref := range refs {
+				if ref.section == section {
+					current[ref.kind+"/"+ref.typ] = ref.id
+				}
+			}
+			for key, id := range current {
+				target := rels[id].Target
+				for i := range res {
+					if res[i].Name != target {
+						continue
+					}
+					if res[i].Section < 0 {
+						res[i].Section, res[i].Type = section, key[strings.Index(key, "/")+1:]
+					}
+					if !slices.Contains(res[i].Sections, section) {
+						res[i].Sections = append(res[i].Sections, section)
+					}
+				}
+			}
+		}
+	}
+
+	rank := make(map[Parts]int)
+	for i, k := range readingOrder {
+		rank[k] = i
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Kind != b.Kind {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		if (a.Section < 0) != (b.Section < 0) { // referenced containers first
+			return a.Section >= 0
+		}
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		if a.Type != b.Type {
+			return referenceTypes[a.Type] < referenceTypes[b.Type]
+		}
+		return a.Name < b.Name
+	})
+	return res, nil
+}
+
+// sectionReference is a header or footer reference found in the properties of a section.
+type sectionReference struct {
+	section int    // index of the section, starting at 0
+	kind    string // header or footer
+	typ     string // default, first or even
+	id      string // relationship id of the header or footer part
+}
+
+// Read the header and footer references of all the sections of the main document, in document order, and the number of sections.
+// Properties recorded for tracked changes (pPrChange, sectPrChange) are ignored.
+func sectionReferences(content []byte) ([]sectionReference, int, error) {
+	var refs []sectionReference
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	section := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return refs, section, nil
+		}
+		if err != nil {
+			return refs, section, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != NAMESPACE {
+				continue
+			}
+			switch t.Name.Local {
+			case "pPrChange", "sectPrChange":
+				if err := dec.Skip(); err != nil {
+					return refs, section, err
+				}
+			case "headerReference", "footerReference":
+				ref := sectionReference{section: section, kind: strings.TrimSuffix(t.Name.Local, "Reference"), typ: "default"}
+				for _, a := range t.Attr {
+					switch {
+					case a.Name.Local == "type" && a.Name.Space == NAMESPACE:
+						ref.typ = a.Value
+					case a.Name.Local == "id" && a.Name.Space == RELATIONSHIPS_NAMESPACE:
+						ref.id = a.Value
+					}
+				}
+				refs = append(refs, ref)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "sectPr" && t.Name.Space == NAMESPACE {
+				section++
+			}
+		}
+	}
+}
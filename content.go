@@ -0,0 +1,378 @@
+package mydocx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Content is a sequence of paragraphs and tables, built to replace a paragraph of a document (see NewContentReplacer).
+// Paragraphs are duplicated from the replaced paragraph : they keep its properties and the properties of its first run,
+// unless a style is chosen. Their text is made of plain text, and of the markup returned by Bold, Italic, Underline, Link or Image.
+//
+//	c := mydocx.NewContent().
+//		Heading(1, "Appendix A").
+//		Paragraph("The ", mydocx.Bold("prices"), " are valid until december.").
+//		BulletItem(0, "first item").
+//		Table("TableGrid", []string{"Product", "Price"}, []string{"Pen", "1.50"}).
+//		PageBreak()
+type Content struct {
+	paras   []string // paragraphs, as returned by a Replacer
+	numbers [9]int   // current values of the numbered list levels, for the labels of the documents without list definitions
+}
+
+// Create an empty content. An empty content removes the replaced paragraph, as an empty Replacer result.
+func NewContent() *Content {
+	return new(Content)
+}
+
+// Create a Replacer from a function returning the content that replaces each paragraph.
+// If the function returns nil, the paragraph is left unchanged.
+func NewContentReplacer(f func(container string, original string) *Content) Replacer {
+	return func(container string, original string) []string {
+		c := f(container, original)
+		if c == nil {
+			return []string{original}
+		}
+		return c.Strings()
+	}
+}
+
+// Get the paragraphs of the content, as returned by a Replacer. Styles, lists, page breaks and tables are encoded as inline objects.
+func (c *Content) Strings() []string {
+	return append([]string(nil), c.paras...)
+}
+
+// Add a paragraph, with the properties of the replaced paragraph, made of the concatenated text parts.
+func (c *Content) Paragraph(text ...string) *Content {
+	c.paras = append(c.paras, strings.Join(text, ""))
+	return c
+}
+
+// Add a paragraph with a paragraph style, identified by its id (eg : Heading1, Title, Quote).
+// The numbering of the replaced paragraph is removed.
+func (c *Content) StyledParagraph(style string, text ...string) *Content {
+	c.paras = append(c.paras, inlineObject("style", style)+strings.Join(text, ""))
+	return c
+}
+
+// Add a heading, from level 1 to 9, with the built-in heading styles (Heading1 ... Heading9).
+func (c *Content) Heading(level int, text ...string) *Content {
+	return c.StyledParagraph("Heading"+strconv.Itoa(min(max(level, 1), 9)), text...)
+}
+
+// Add an item of a bulleted list, at a level from 0 to 8.
+// The item uses a bulleted list definition of the document, if any, or else a bullet and an indentation.
+func (c *Content) BulletItem(level int, text ...string) *Content {
+	return c.listItem("bullet", level, text)
+}
+
+// Add an item of a numbered list, at a level from 0 to 8. Items are numbered by Word, as the items of a list of the document.
+// The item uses a decimal list definition of the document, if any, or else a number and an indentation.
+// The numbered items of a Content restart at the start of the list, unless they replace an item of a numbered list.
+func (c *Content) NumberedItem(level int, text ...string) *Content {
+	return c.listItem("number", level, text)
+}
+
+// Add a list item, of kind bullet or number.
+func (c *Content) listItem(kind string, level int, text []string) *Content {
+	level = min(max(level, 0), 8)
+	if kind == "number" {
+		c.numbers[level]++
+		for i := level + 1; i < len(c.numbers); i++ {
+			c.numbers[i] = 0
+		}
+	}
+	item := inlineObject("list", kind, strconv.Itoa(level), strconv.Itoa(c.numbers[level]))
+	c.paras = append(c.paras, item+strings.Join(text, ""))
+	return c
+}
+
+// Add a page break, in an empty paragraph.
+func (c *Content) PageBreak() *Content {
+	c.paras = append(c.paras, PageBreak())
+	return c
+}
+
+// Add a table, with a table style identified by its id (eg : TableGrid), or simple borders if the style is empty.
+// The first row is the header row, repeated on each page. Cells hold plain text, with the run properties of the replaced paragraph.
+func (c *Content) Table(style string, rows ...[]string) *Content {
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	if cols == 0 {
+		return c
+	}
+	args := []string{style, strconv.Itoa(cols)}
+	for _, r := range rows {
+		args = append(args, r...)
+		for i := len(r); i < cols; i++ {
+			args = append(args, "")
+		}
+	}
+	c.paras = append(c.paras, inlineObject("table", args...))
+	return c
+}
+
+// Bold returns the markup of a text in bold, for inclusion in the text returned by a Replacer, or in a Content.
+// Formats can be combined : Bold(Italic("text")).
+func Bold(text string) string {
+	return addFormat(text, 'b')
+}
+
+// Italic returns the markup of a text in italic, see Bold.
+func Italic(text string) string {
+	return addFormat(text, 'i')
+}
+
+// Underline returns the markup of an underlined text, see Bold.
+func Underline(text string) string {
+	return addFormat(text, 'u')
+}
+
+// PageBreak returns the markup of a page break, for inclusion in the text returned by a Replacer.
+// PageBreak is available in templates as {{pageBreak}}.
+func PageBreak() string {
+	return inlineObject("break", "page")
+}
+
+// Get the arguments of a table (style, number of columns, cells), if the text is a table of a Content, or nil.
+func tableArgs(text string) []string {
+	segments := parseInline(text)
+	if len(segments) != 1 || segments[0].kind != "table" || len(segments[0].args) < 2 {
+		return nil
+	}
+	return segments[0].args
+}
+
+// blockProps are the paragraph properties chosen by a Content for a paragraph.
+type blockProps struct {
+	style  string // paragraph style id
+	list   string // list kind : bullet or number
+	level  int    // list level
+	number string // value of the numbered item, for the label of the documents without list definitions
+}
+
+// Split the paragraph properties chosen by a Content from the text of a paragraph. Returns nil if the text has none.
+func splitBlockProps(text string) (*blockProps, string) {
+	if !hasInline(text) {
+		return nil, text
+	}
+	var props *blockProps
+	var sb strings.Builder
+	for _, seg := range parseInline(text) {
+		switch {
+		case seg.kind == "":
+			sb.WriteString(seg.text)
+		case seg.kind == "style" && len(seg.args) == 1:
+			if props == nil {
+				props = new(blockProps)
+			}
+			props.style = seg.args[0]
+		case seg.kind == "list" && len(seg.args) == 3:
+			if props == nil {
+				props = new(blockProps)
+			}
+			props.list, props.number = seg.args[0], seg.args[2]
+			props.level, _ = strconv.Atoi(seg.args[1])
+		default:
+			sb.WriteString(inlineObject(seg.kind, seg.args...))
+		}
+	}
+	return props, sb.String()
+}
+
+// blockBase holds the properties of a replaced paragraph, before its modification,
+// to build the paragraphs and tables of a Content.
+type blockBase struct {
+	pPr       *xnode            // paragraph properties, or nil
+	rPr       *xnode            // properties of the first run, or nil
+	lists     map[string]string // numbering instance of the list kinds (bullet, number), loaded on first use
+	continued bool              // the numbered items use the numbering of the replaced paragraph, or an already restarted one
+	para      *xnode            // replaced paragraph
+	source    *xnode            // copy of the replaced paragraph, to find its numbering
+	changed   bool              // the properties of the paragraph were changed : its duplicates are restored first
+}
+
+// Get the properties of a paragraph, before its modification.
+func newBlockBase(p *xnode) *blockBase {
+	b := &blockBase{para: p, source: p.clone()}
+	if pPr := p.child("pPr"); pPr != nil {
+		b.pPr = pPr.clone()
+	}
+	if texts := paragraphTexts(p, acceptedView); len(texts) > 0 {
+		if run := texts[0].ancestor("r"); run != nil && run.child("rPr") != nil {
+			b.rPr = run.child("rPr").clone()
+		}
+	}
+	return b
+}
+
+// Save the text of a paragraph of a Content in a paragraph, applying its paragraph properties.
+// The paragraph is either the replaced paragraph, or one of its duplicates.
+func (md *modifier) setBlockText(p *xnode, text string, b *blockBase) {
+	props, text := splitBlockProps(text)
+	if b.changed && p != b.para {
+		if pPr := p.child("pPr"); pPr != nil {
+			pPr.remove()
+		}
+		if b.pPr != nil {
+			p.insertChildren(0, b.pPr.clone())
+		}
+	}
+	if props != nil {
+		text = md.setBlockProps(p, props, b) + text
+		b.changed = b.changed || p == b.para
+	}
+	md.setParagraphText(p, text)
+}
+
+// Apply the paragraph properties of a Content to a paragraph, and return the label to insert before the text, if any.
+func (md *modifier) setBlockProps(p *xnode, props *blockProps, b *blockBase) string {
+	if props.style != "" {
+		setParagraphProperty(p, `<w:pStyle w:val="`+string(xmlEscape([]byte(props.style)))+`"/>`)
+		removeParagraphProperty(p, "numPr") // the numbering of the style applies
+	}
+	if props.list == "" {
+		return ""
+	}
+	if numID := md.listNumbering(props.list, b); numID != "" {
+		setParagraphProperty(p, `<w:numPr><w:ilvl w:val="`+strconv.Itoa(props.level)+`"/><w:numId w:val="`+numID+`"/></w:numPr>`)
+		return ""
+	}
+	// no list definition : an indented paragraph, with a label and a tab
+	removeParagraphProperty(p, "numPr")
+	setParagraphProperty(p, `<w:ind w:left="`+strconv.Itoa(360*(props.level+2))+`" w:hanging="360"/>`)
+	if props.list == "number" {
+		return props.number + ".\t"
+	}
+	return "•\t"
+}
+
+// Get the numbering instance used for a list kind (bullet or number), or an empty string if the document has none.
+// The numbering of the replaced paragraph is preferred, otherwise the first numbering instance whose first level
+// is bulleted or decimal is used : the numbered items then restart, with a new instance of its list definition.
+func (md *modifier) listNumbering(kind string, b *blockBase) string {
+	if b.lists == nil {
+		b.lists = make(map[string]string)
+		if md.pkg == nil {
+			return ""
+		}
+		ld, err := md.pkg.listDefinitions()
+		if err != nil {
+			md.debug("failed to load the numbering definitions", err)
+			return ""
+		}
+		for k, numID := range ld.defaults {
+			b.lists[k] = numID
+		}
+		if numID, _ := ld.nb.paragraphNumbering(b.source); numID != "" {
+			if k := ld.kind(numID); k != "" {
+				b.lists[k] = numID
+				b.continued = b.continued || k == "number"
+			}
+		}
+	}
+	if kind == "number" && !b.continued && b.lists[kind] != "" {
+		b.lists[kind] = md.pkg.restartNumbering(b.lists[kind])
+		b.continued = true
+	}
+	return b.lists[kind]
+}
+
+// Order of the elements of the paragraph properties, as required by the schema.
+var paragraphPropertiesOrder = []string{"pStyle", "keepNext", "keepLines", "pageBreakBefore", "framePr", "widowControl",
+	"numPr", "suppressLineNumbers", "pBdr", "shd", "tabs", "suppressAutoHyphens", "kinsoku", "wordWrap", "overflowPunct",
+	"topLinePunct", "autoSpaceDE", "autoSpaceDN", "bidi", "adjustRightInd", "snapToGrid", "spacing", "ind",
+	"contextualSpacing", "mirrorIndents", "suppressOverlap", "jc", "textDirection", "textAlignment", "textboxTightWrap",
+	"outlineLvl", "divId", "cnfStyle", "rPr", "sectPr", "pPrChange"}
+
+// Set a paragraph property (eg : <w:pStyle w:val="Heading1"/>), replacing the existing one, in schema order.
+func setParagraphProperty(p *xnode, property string) {
+	nodes, err := parseFragment(property)
+	if err != nil || len(nodes) != 1 {
+		panic("invalid paragraph property : " + property) // should never happen
+	}
+	prop := nodes[0]
+	pPr := p.child("pPr")
+	if pPr == nil {
+		nodes, _ := parseFragment(`<w:pPr></w:pPr>`)
+		pPr = nodes[0]
+		p.insertChildren(0, pPr)
+	}
+	rank := func(n *xnode) int {
+		for i, local := range paragraphPropertiesOrder {
+			if n.is(local) {
+				return i
+			}
+		}
+		return len(paragraphPropertiesOrder)
+	}
+	for i, c := range pPr.children {
+		switch {
+		case !c.isElement():
+		case c.name == prop.name:
+			c.remove()
+			pPr.insertChildren(i, prop)
+			return
+		case rank(c) > rank(prop):
+			pPr.insertChildren(i, prop)
+			return
+		}
+	}
+	pPr.appendChildren(prop)
+}
+
+// Remove a paragraph property, if set.
+func removeParagraphProperty(p *xnode, local string) {
+	if pPr := p.child("pPr"); pPr != nil {
+		if c := pPr.child(local); c != nil {
+			c.remove()
+		}
+	}
+}
+
+// Build a table of a Content, from its arguments (see tableArgs). Cell text has the run properties of the replaced paragraph.
+func (md *modifier) table(args []string, b *blockBase) *xnode {
+	style, cells := args[0], args[2:]
+	cols, _ := strconv.Atoi(args[1])
+	cols = max(cols, 1)
+	rPr := mergeRunProperties(b.rPr)
+
+	var sb strings.Builder
+	sb.WriteString(`<w:tbl><w:tblPr>`)
+	if style != "" {
+		sb.WriteString(`<w:tblStyle w:val="` + string(xmlEscape([]byte(style))) + `"/>`)
+	}
+	sb.WriteString(`<w:tblW w:w="5000" w:type="pct"/>`)
+	if style == "" {
+		sb.WriteString(`<w:tblBorders>`)
+		for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+			sb.WriteString(`<w:` + side + ` w:val="single" w:sz="4" w:space="0" w:color="auto"/>`)
+		}
+		sb.WriteString(`</w:tblBorders>`)
+	}
+	sb.WriteString(`<w:tblLook w:val="04A0" w:firstRow="1" w:lastRow="0" w:firstColumn="1" w:lastColumn="0" w:noHBand="0" w:noVBand="1"/></w:tblPr><w:tblGrid>`)
+	sb.WriteString(strings.Repeat(`<w:gridCol/>`, cols))
+	sb.WriteString(`</w:tblGrid>`)
+	for i := 0; i < len(cells); i += cols {
+		sb.WriteString(`<w:tr>`)
+		if i == 0 {
+			sb.WriteString(`<w:trPr><w:tblHeader/></w:trPr>`)
+		}
+		for _, cell := range cells[i:min(i+cols, len(cells))] {
+			sb.WriteString(`<w:tc><w:tcPr><w:tcW w:w="0" w:type="auto"/></w:tcPr><w:p>`)
+			if cell != "" {
+				sb.WriteString(textRun(rPr, cell))
+			}
+			sb.WriteString(`</w:p></w:tc>`)
+		}
+		sb.WriteString(`</w:tr>`)
+	}
+	sb.WriteString(`</w:tbl>`)
+	nodes, err := parseFragment(sb.String())
+	if err != nil {
+		panic("invalid table fragment : " + err.Error()) // should never happen
+	}
+	return nodes[0]
+}
@@ -0,0 +1,129 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContent(t *testing.T) {
+
+	appendix := func(_, original string) *Content {
+		if original != "{{appendix}}" {
+			return nil
+		}
+		return NewContent().
+			Heading(1, "Appendix").
+			Paragraph("Prices are ", Bold("firm"), ".").
+			BulletItem(0, "first").
+			NumberedItem(0, "one").
+			NumberedItem(1, "detail").
+			NumberedItem(0, "two").
+			Table("", []string{"Product", "Price"}, []string{"Pen"}).
+			PageBreak().
+			StyledParagraph("Heading2", "End")
+	}
+	body := testPara("Intro") +
+		`<w:p><w:r><w:rPr><w:i/></w:rPr><w:t>{{appendix}}</w:t></w:r></w:p>` +
+		testPara("Outro")
+
+	// with the list definitions of the document : the numbered items restart, instead of continuing an existing list
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:     testContentTypes,
+		"word/document.xml":  testDocument(testStyledPara(`<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr>`, "Existing") + body),
+		"word/numbering.xml": testNumbering,
+		"word/styles.xml":    testStyles,
+	})
+	out, err := ModifyTextBytes(docx, NewContentReplacer(appendix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out, WithNumbering())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1. Existing", "Intro", "I. Appendix", "Prices are firm.", "• first", "1. one", "1.1 detail", "2. two",
+		"Product", "Price", "Pen", "", "", "End", "Outro"}
+	if got := pp["word/document.xml"]; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	content := readPart(t, out, "word/document.xml")
+	for _, s := range []string{
+		`<w:pPr><w:pStyle w:val="Heading1"/></w:pPr>`,
+		`<w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="5"/></w:numPr></w:pPr>`,
+		`<w:r><w:rPr><w:b/><w:bCs/><w:i/></w:rPr><w:t xml:space="preserve">firm</w:t></w:r>`,
+		`<w:trPr><w:tblHeader/></w:trPr><w:tc><w:tcPr><w:tcW w:w="0" w:type="auto"/></w:tcPr><w:p><w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">Product</w:t></w:r></w:p></w:tc>`,
+		`<w:br w:type="page"/>`,
+	} {
+		if !strings.Contains(content, s) {
+			t.Errorf("missing %s in :\n%s", s, content)
+		}
+	}
+	if strings.Count(content, `<w:pStyle`) != 2 {
+		t.Errorf("the style of the heading should not be copied to the following paragraphs :\n%s", content)
+	}
+	if content = readPart(t, out, "word/numbering.xml"); !strings.Contains(content,
+		`<w:num w:numId="5"><w:abstractNumId w:val="0"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride></w:num></w:numbering>`) {
+		t.Errorf("missing numbering instance in :\n%s", content)
+	}
+
+	// without list definitions, labels are inserted in the text
+	docx = makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	out, err = ModifyTextBytes(docx, NewContentReplacer(appendix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err = ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"Intro", "Appendix", "Prices are firm.", "•\tfirst", "1.\tone", "1.\tdetail", "2.\ttwo",
+		"Product", "Price", "Pen", "", "", "End", "Outro"}
+	if got := pp["word/document.xml"]; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// a content made of a table replaces the paragraph, a nil content leaves it unchanged
+	out, err = ModifyTextBytes(docx, NewContentReplacer(func(_, original string) *Content {
+		if original == "Intro" {
+			return NewContent().Table("TableGrid", []string{"A", "B"})
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = readPart(t, out, "word/document.xml")
+	if !strings.Contains(content, `<w:tblStyle w:val="TableGrid"/>`) || strings.Contains(content, "Intro") || !strings.Contains(content, "{{appendix}}") {
+		t.Errorf("unexpected content :\n%s", content)
+	}
+
+	// page breaks in templates
+	docx = makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("End of part{{pageBreak}}")),
+	})
+	out, err = ModifyTextBytes(docx, NewTplReplacer(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content = readPart(t, out, "word/document.xml"); !strings.Contains(content, `<w:r><w:br w:type="page"/></w:r>`) {
+		t.Errorf("missing page break in :\n%s", content)
+	}
+}
+
+// Read a part of a docx file.
+func readPart(t *testing.T, docx []byte, name string) string {
+	t.Helper()
+	pkg, err := openPackage(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := pkg.read(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
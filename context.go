@@ -0,0 +1,139 @@
+package mydocx
+
+import (
+	"fmt"
+)
+
+// ParagraphContext describes a paragraph submitted to a ContextReplacer, as found in the source document.
+type ParagraphContext struct {
+	// Name of the container of the paragraph (eg : word/document.xml)
+	Container string
+	// Index of the paragraph within its container, in the source document, as listed by ExtractText
+	Index int
+	// Style id of the paragraph (eg : Heading1, ListParagraph), or of the default paragraph style
+	Style string
+	// Heading level, from 1 to 9, or 0 for body text (see ExtractOutline)
+	Heading int
+	// Level of the list item (0 based), or -1 if the paragraph is not numbered
+	NumberingLevel int
+	// The paragraph is in a table cell, at Row and Column of the innermost table (0 based, merged cells count as one)
+	InTable     bool
+	Row, Column int
+	// Text of the previous and of the next paragraph of the container, empty for the first and last paragraphs
+	Previous, Next string
+	// Index of the section of the document (0 based), always 0 in headers, footers and notes
+	Section int
+}
+
+// A ContextReplacer replaces the text of a paragraph, as a Replacer, given the context of the paragraph.
+// It can apply different rules to headings, list items, body text or table cells.
+// A Content can be returned with its Strings method.
+type ContextReplacer func(ctx ParagraphContext, original string) (replaced []string)
+
+// Same as ModifyText, with a ContextReplacer.
+func ModifyTextContext(sourceFilePath string, replace ContextReplacer, targetFilePath string, opts ...Option) error {
+	return defaultProcessor().ModifyTextContext(sourceFilePath, replace, targetFilePath, opts...)
+}
+
+// Same as ModifyTextContext, with the settings of the Processor.
+func (p *Processor) ModifyTextContext(sourceFilePath string, replace ContextReplacer, targetFilePath string, opts ...Option) error {
+	if targetFilePath == "" {
+		targetFilePath = sourceFilePath
+	}
+	if p.Verbose {
+		fmt.Println("Modifying : ", sourceFilePath, "-->", targetFilePath)
+	}
+	return modifyFile(sourceFilePath, targetFilePath, "modify text", func(in []byte) ([]byte, error) {
+		return p.ModifyTextContextBytes(in, replace, opts...)
+	})
+}
+
+// Same as ModifyTextBytes, with a ContextReplacer.
+func ModifyTextContextBytes(sourceBytes []byte, replace ContextReplacer, opts ...Option) ([]byte, error) {
+	return defaultProcessor().ModifyTextContextBytes(sourceBytes, replace, opts...)
+}
+
+// Same as ModifyTextContextBytes, with the settings of the Processor.
+// A nil ContextReplacer copies the text unmodified, as a nil Replacer.
+func (p *Processor) ModifyTextContextBytes(sourceBytes []byte, replace ContextReplacer, opts ...Option) ([]byte, error) {
+	if replace == nil {
+		return p.ModifyTextBytes(sourceBytes, nil, opts...)
+	}
+	return p.modifyTextBytes(sourceBytes, nil, replace, opts)
+}
+
+// Compute the context of the paragraphs of a container tree, before any modification.
+// Styles and numbering are those of the package, that may be nil.
+func (md *modifier) paragraphContexts(root *xnode) map[*xnode]ParagraphContext {
+	var nb *numbering
+	if md.pkg != nil {
+		var err error
+		if nb, err = md.pkg.numbering(); err != nil {
+			md.debug("failed to load the styles and numbering definitions", err)
+			nb = nil
+		}
+	}
+	paras := root.paragraphs()
+	texts := make([]string, len(paras))
+	for i, p := range paras {
+		texts[i] = paragraphText(p, acceptedView)
+	}
+	res := make(map[*xnode]ParagraphContext, len(paras))
+	section := 0
+	for i, p := range paras {
+		ctx := ParagraphContext{Container: md.container, Index: i, NumberingLevel: -1, Section: section}
+		if nb != nil {
+			ctx.Style = nb.styles.paragraphStyleID(p)
+			ctx.Heading = nb.styles.headingLevel(p)
+			if numID, ilvl := nb.paragraphNumbering(p); numID != "" {
+				ctx.NumberingLevel = ilvl
+			}
+		} else if pPr := p.child("pPr"); pPr != nil && pPr.child("pStyle") != nil {
+			ctx.Style = pPr.child("pStyle").val()
+		}
+		ctx.Row, ctx.Column, ctx.InTable = cellPosition(p)
+		if i > 0 {
+			ctx.Previous = texts[i-1]
+		}
+		if i+1 < len(texts) {
+			ctx.Next = texts[i+1]
+		}
+		if pPr := p.child("pPr"); pPr != nil && pPr.child("sectPr") != nil {
+			section++ // the paragraph ends its section
+		}
+		res[p] = ctx
+	}
+	return res
+}
+
+// Get the row and the column of the cell of the innermost table holding a paragraph.
+// Returns false if the paragraph is not in a table.
+func cellPosition(p *xnode) (row int, col int, ok bool) {
+	tc := p.ancestor("tc")
+	if tc == nil {
+		return 0, 0, false
+	}
+	tr := tc.ancestor("tr")
+	if tr == nil {
+		return 0, 0, false
+	}
+	if tbl := tr.ancestor("tbl"); tbl != nil {
+		for _, r := range tbl.collect("tr") {
+			if r == tr {
+				break
+			}
+			if r.ancestor("tbl") == tbl {
+				row++
+			}
+		}
+	}
+	for _, c := range tr.collect("tc") {
+		if c == tc {
+			break
+		}
+		if c.ancestor("tr") == tr {
+			col++
+		}
+	}
+	return row, col, true
+}
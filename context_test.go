@@ -0,0 +1,61 @@
+package mydocx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParagraphContext(t *testing.T) {
+
+	cell := func(text string) string { return `<w:tc>` + testPara(text) + `</w:tc>` }
+	body := testStyledPara(`<w:pStyle w:val="Heading1"/>`, "Title") +
+		testListPara(1, 1, "Item") +
+		testStyledPara(`<w:sectPr/>`, "End of section") +
+		`<w:tbl><w:tr>` + cell("A1") + cell("B1") + `</w:tr><w:tr>` + cell("A2") + cell("B2") + `</w:tr></w:tbl>` +
+		testPara("Last")
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:     testContentTypes,
+		"word/document.xml":  testDocument(body),
+		"word/numbering.xml": testNumbering,
+		"word/styles.xml":    testStyles,
+	})
+
+	var got []string
+	out, err := ModifyTextContextBytes(docx, func(ctx ParagraphContext, text string) []string {
+		got = append(got, fmt.Sprintf("%d %s h%d n%d t%v %d,%d s%d %q<%s>%q", ctx.Index, ctx.Style, ctx.Heading, ctx.NumberingLevel,
+			ctx.InTable, ctx.Row, ctx.Column, ctx.Section, ctx.Previous, text, ctx.Next))
+		switch {
+		case ctx.Heading > 0:
+			return []string{strings.ToUpper(text)}
+		case ctx.InTable && ctx.Row == 0:
+			return []string{"Header " + text}
+		}
+		return []string{text}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`0 Heading1 h1 n0 tfalse 0,0 s0 ""<Title>"Item"`,
+		`1 Normal h0 n1 tfalse 0,0 s0 "Title"<Item>"End of section"`,
+		`2 Normal h0 n-1 tfalse 0,0 s0 "Item"<End of section>"A1"`,
+		`3 Normal h0 n-1 ttrue 0,0 s1 "End of section"<A1>"B1"`,
+		`4 Normal h0 n-1 ttrue 0,1 s1 "A1"<B1>"A2"`,
+		`5 Normal h0 n-1 ttrue 1,0 s1 "B1"<A2>"B2"`,
+		`6 Normal h0 n-1 ttrue 1,1 s1 "A2"<B2>"Last"`,
+		`7 Normal h0 n-1 tfalse 0,0 s1 "B2"<Last>""`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got :\n%s\nwant :\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(pp["word/document.xml"], "|"); text != "TITLE|Item|End of section|Header A1|Header B1|A2|B2|Last" {
+		t.Errorf("unexpected text : %q", text)
+	}
+}
@@ -0,0 +1,146 @@
+package mydocx
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Default delimiters of the template actions.
+const (
+	leftDelim  = "{{"
+	rightDelim = "}}"
+)
+
+// Directive changing the delimiters of the template actions, for the rest of the document (eg : {{delims "[[" "]]"}}).
+var delimsDirective = regexp.MustCompile(`^delims\s+"([^"]+)"\s+"([^"]+)"$`)
+
+// Escape the default delimiters in the text around the actions, when other delimiters are used.
+var delimEscaper = strings.NewReplacer(leftDelim, leftDelim+`"`+leftDelim+`"`+rightDelim, rightDelim, leftDelim+`"`+rightDelim+`"`+rightDelim)
+
+// tplReader gets the template source of the paragraphs of a document, in document order, with the default delimiters.
+// It applies the delimiters of the processor, changed by the {{delims}} directives, and escapes the text of the literal regions,
+// from {{literal}} to {{endLiteral}}, that may span several paragraphs.
+type tplReader struct {
+	left, right string
+	end         *regexp.Regexp // end of a literal region, with the current delimiters
+	literal     bool           // within a literal region
+}
+
+// Create a reader of the template source, with the delimiters of the processor.
+func (p *Processor) reader() *tplReader {
+	r := new(tplReader)
+	r.delims(p.LeftDelim, p.RightDelim)
+	return r
+}
+
+// Get a reader of the template source of a paragraph, resuming at the state that prefixes its text, if any (see tplReader.state),
+// with the text of the paragraph.
+func (p *Processor) resume(text string) (*tplReader, string) {
+	r := p.reader()
+	args, found := readerState(text)
+	if !found || len(args) != 3 {
+		return r, text
+	}
+	r.delims(args[0], args[1])
+	r.literal = args[2] != ""
+	return r, strings.TrimPrefix(text, inlineObject("reader", args...))
+}
+
+// Get the state of the reader, to resume reading at the next paragraph : an inline object, without arguments for the initial state.
+func (r *tplReader) state(initial *tplReader) string {
+	if r.left == initial.left && r.right == initial.right && !r.literal {
+		return inlineObject("reader")
+	}
+	literal := ""
+	if r.literal {
+		literal = "literal"
+	}
+	return inlineObject("reader", r.left, r.right, literal)
+}
+
+// Get the arguments of the reader state that starts the text, if any.
+func readerState(text string) (args []string, found bool) {
+	if !strings.HasPrefix(text, inlineStart+"reader") {
+		return nil, false
+	}
+	seg := parseInline(text)[0]
+	return seg.args, seg.kind == "reader"
+}
+
+// Change the delimiters. Empty delimiters are the default ones.
+func (r *tplReader) delims(left, right string) {
+	r.left, r.right = left, right
+	if left == "" {
+		r.left = leftDelim
+	}
+	if right == "" {
+		r.right = rightDelim
+	}
+	r.end = regexp.MustCompile(regexp.QuoteMeta(r.left) + `-?\s*endLiteral\s*-?` + regexp.QuoteMeta(r.right))
+}
+
+// Get the template source of the next paragraph, from its text.
+func (r *tplReader) source(text string) string {
+	var sb strings.Builder
+	for text != "" {
+		if r.literal {
+			lit, rest := text, ""
+			if loc := r.end.FindStringIndex(text); loc != nil {
+				lit, rest, r.literal = text[:loc[0]], text[loc[1]:], false
+			}
+			if lit != "" {
+				sb.WriteString(leftDelim + strconv.Quote(lit) + rightDelim)
+			}
+			if !r.literal {
+				sb.WriteString(directiveComment("endLiteral"))
+			}
+			text = rest
+			continue
+		}
+		directive := false
+		for _, a := range scanDelimited(text, r.left, r.right) {
+			var body strings.Builder
+			normalizeAction(&body, a.body)
+			m := delimsDirective.FindStringSubmatch(body.String())
+			if body.String() != "literal" && m == nil {
+				continue
+			}
+			r.translate(&sb, text[:a.start])
+			if m != nil {
+				sb.WriteString(directiveComment("delims"))
+				r.delims(m[1], m[2])
+			} else {
+				sb.WriteString(directiveComment("literal"))
+				r.literal = true
+			}
+			text, directive = text[a.end:], true
+			break
+		}
+		if !directive {
+			r.translate(&sb, text)
+			break
+		}
+	}
+	return sb.String()
+}
+
+// Get the template source replacing a directive : a comment, so that a paragraph holding only directives is discarded, as an empty template result.
+func directiveComment(name string) string {
+	return leftDelim + "/* " + name + " */" + rightDelim
+}
+
+// Write the template source of a text without directives, with the default delimiters.
+func (r *tplReader) translate(sb *strings.Builder, text string) {
+	if r.left == leftDelim && r.right == rightDelim {
+		sb.WriteString(text)
+		return
+	}
+	pos := 0
+	for _, a := range scanDelimited(text, r.left, r.right) {
+		sb.WriteString(delimEscaper.Replace(text[pos:a.start]))
+		sb.WriteString(leftDelim + text[a.start+len(r.left):a.end-len(r.right)] + rightDelim)
+		pos = a.end
+	}
+	sb.WriteString(delimEscaper.Replace(text[pos:]))
+}
@@ -0,0 +1,92 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateDelimiters(t *testing.T) {
+
+	// template source, paragraph by paragraph
+	r := NewProcessor().reader()
+	for _, tc := range []struct{ text, want string }{
+		{"Hello {{.Name}} }}", "Hello {{.Name}} }}"}, // unchanged with the default delimiters
+		{"Code : {{literal}}x := {{.Y}}{{endLiteral}} for {{.Name}}", `Code : {{/* literal */}}{{"x := {{.Y}}"}}{{/* endLiteral */}} for {{.Name}}`},
+		{"{{literal}}", "{{/* literal */}}"},
+		{"func f() {{ return }}", `{{"func f() {{ return }}"}}`},
+		{"{{- endLiteral -}}", "{{/* endLiteral */}}"},
+		{"{{delims “[[” “]]”}}", "{{/* delims */}}"}, // autocorrected directive
+		{"[[.Name]] {{x}} [[- if .OK -]]", `{{.Name}} {{"{{"}}x{{"}}"}} {{- if .OK -}}`},
+		{`[[printf "]]" .Name]]`, `{{printf "]]" .Name}}`},
+		{`[[delims "<<" ">>"]]<<.Name>>`, "{{/* delims */}}{{.Name}}"},
+	} {
+		if got := r.source(tc.text); got != tc.want {
+			t.Errorf("source(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+
+	// delimiters of a processor, and directives, over the paragraphs of a document
+	p := NewProcessor()
+	p.LeftDelim, p.RightDelim = "[[", "]]"
+	replace := p.NewTplReplacer(map[string]string{"Name": "Ann"})
+	para := func(text string) string { return testPara(string(xmlEscape([]byte(text)))) }
+	body := para("Hello [[.Name]], {{not a template}}") + para(`[[delims "<%" "%>"]]`) + para("Bye <%.Name%> [[x]]") +
+		para("<%literal%>") + para("<%.Name%>") + para("<%endLiteral%><%.Name%>") + para("<%endLiteral%>") + para("<%literal%>")
+	want := "Hello Ann, {{not a template}}|Bye Ann [[x]]|<%.Name%>|Ann|<%endLiteral%>|" + errorBanner +
+		`template: mydocx_template:1:2: executing "mydocx_template" at <endLiteral>: error calling endLiteral: {{endLiteral}} without {{literal}} `
+	if got := modifyTestText(t, p, body, replace); got != want {
+		t.Errorf("unexpected result\ngot  %q\nwant %q", got, want)
+	}
+
+	// the same replacer on the next document, that starts with the delimiters of the processor, outside of a literal region
+	if got := modifyTestText(t, p, para("[[.Name]] <%.Name%>"), replace); got != "Ann <%.Name%>" {
+		t.Errorf("unexpected result of the next document %q", got)
+	}
+	if got := replace("", "<%.Name%> [[.Name]]"); strings.Join(got, "|") != "<%.Name%> Ann" {
+		t.Errorf("unexpected result of a paragraph on its own %q", got)
+	}
+
+	// documents, with blocks and literal regions spanning paragraphs
+	body = testPara("{{delims \"[[\" \"]]\"}}") + testPara("[[range .Items]]") + testPara("- [[.]] {{x}}") + testPara("[[end]]") +
+		testPara("[[literal]]") + testPara("[[range .Items]]") + testPara("[[endLiteral]]") + testPara("[[.Nmae]]")
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	out, err := ExecuteTemplateBytes(docx, map[string]any{"Items": []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pp["word/document.xml"], "|"); got != "- a {{x}}|- b {{x}}|[[range .Items]]|<no value>" {
+		t.Errorf("unexpected result %q", got)
+	}
+	errs, err := ValidateTemplateBlocks(docx, struct{ Items []string }{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Paragraph != 7 || !strings.Contains(errs[0].Message, "Nmae") {
+		t.Errorf("unexpected errors : %v", errs)
+	}
+}
+
+// Modify a document of the body paragraphs with the processor and the replacer, and get its text, paragraphs separated by |.
+func modifyTestText(t *testing.T, p *Processor, body string, replace Replacer) string {
+	t.Helper()
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	out, err := p.ModifyTextBytes(docx, replace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(pp["word/document.xml"], "|")
+}
@@ -2,6 +2,7 @@ package mydocx
 
 import (
 	"archive/zip"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -250,3 +251,122 @@ func TestDocExtractOriginal(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractContainers(t *testing.T) {
+
+	cc, err := ExtractContainers(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expected reading order, with section and reference types
+	want := []Container{
+		{Name: "word/header3.xml", Kind: PartHeaders, Section: 0, Type: "first"},
+		{Name: "word/header2.xml", Kind: PartHeaders, Section: 0, Type: "default"},
+		{Name: "word/header1.xml", Kind: PartHeaders, Section: 0, Type: "even"},
+		{Name: "word/document.xml", Kind: PartBody, Section: -1},
+		{Name: "word/footer3.xml", Kind: PartFooters, Section: 0, Type: "first"},
+		{Name: "word/footer2.xml", Kind: PartFooters, Section: 0, Type: "default"},
+		{Name: "word/footer1.xml", Kind: PartFooters, Section: 0, Type: "even"},
+	}
+	if len(cc) != len(want) {
+		t.Fatalf("got %d containers, want %d", len(cc), len(want))
+	}
+	for i, c := range cc {
+		w := want[i]
+		if c.Name != w.Name || c.Kind != w.Kind || c.Section != w.Section || c.Type != w.Type {
+			t.Errorf("container %d : got %s %b %d %q, want %s %b %d %q", i, c.Name, c.Kind, c.Section, c.Type, w.Name, w.Kind, w.Section, w.Type)
+		}
+		if len(c.Paragraphs) == 0 {
+			t.Errorf("container %s has no paragraph", c.Name)
+		}
+	}
+}
+
+func TestInheritedHeaders(t *testing.T) {
+
+	// three sections : the second one inherits the header of the first one, the third one has its own header
+	types := strings.Replace(testContentTypes, `</Types>`, `<Override PartName="/word/header2.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/></Types>`, 1)
+	rels := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="myHeader.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header2.xml"/></Relationships>`
+	header := func(text string) string {
+		return `<w:hdr ` + testNamespaces + `>` + testPara(text) + `</w:hdr>`
+	}
+	body := `<w:p><w:pPr><w:sectPr><w:headerReference w:type="default" r:id="rId1"/></w:sectPr></w:pPr><w:r><w:t>one</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:sectPr/></w:pPr><w:r><w:t>two</w:t></w:r></w:p>` +
+		testPara("three") + `<w:sectPr><w:headerReference w:type="default" r:id="rId2"/></w:sectPr>`
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:               types,
+		"word/document.xml":            testDocument(body),
+		"word/_rels/document.xml.rels": rels,
+		"word/myHeader.xml":            header("first header"),
+		"word/header2.xml":             header("second header"),
+	})
+	cc, err := ExtractContainersBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range cc {
+		got = append(got, fmt.Sprintf("%s %d %q %v", c.Name, c.Section, c.Type, c.Sections))
+	}
+	want := []string{`word/myHeader.xml 0 "default" [0 1]`, `word/header2.xml 2 "default" [2]`, `word/document.xml -1 "" []`}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// anchor paragraph, with a text box in both the DrawingML choice and the VML fallback
+const testTextBox = `<w:p><w:r><w:t xml:space="preserve">Anchor </w:t></w:r><w:r><mc:AlternateContent><mc:Choice Requires="wps"><w:drawing><wp:anchor><a:graphic><a:graphicData><wps:wsp><wps:txbx><w:txbxContent><w:p><w:r><w:t>Street</w:t></w:r></w:p><w:p><w:r><w:t>City</w:t></w:r></w:p></w:txbxContent></wps:txbx></wps:wsp></a:graphicData></a:graphic></wp:anchor></w:drawing></mc:Choice><mc:Fallback><w:pict><v:shape><v:textbox><w:txbxContent><w:p><w:r><w:t>Street</w:t></w:r></w:p><w:p><w:r><w:t>City</w:t></w:r></w:p></w:txbxContent></v:textbox></v:shape></w:pict></mc:Fallback></mc:AlternateContent></w:r><w:r><w:t>text</w:t></w:r></w:p>`
+
+func TestTextBox(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testTextBox + testPara("After")),
+	})
+
+	pp, err := ExtractTextBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Anchor text", "Street", "City", "After"}
+	if fmt.Sprint(pp["word/document.xml"]) != fmt.Sprint(want) {
+		t.Fatalf("got %q, want %q", pp["word/document.xml"], want)
+	}
+
+	// replacer is called once per paragraph, fallback receives the same result
+	calls := 0
+	out, err := ModifyTextBytes(docx, func(_, s string) []string {
+		calls++
+		return []string{strings.ToUpper(s)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != len(want) {
+		t.Errorf("replacer called %d times, want %d", calls, len(want))
+	}
+	pp, err = ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"ANCHOR TEXT", "STREET", "CITY", "AFTER"}
+	if fmt.Sprint(pp["word/document.xml"]) != fmt.Sprint(want) {
+		t.Fatalf("got %q, want %q", pp["word/document.xml"], want)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			content, _ := readFile(f)
+			if strings.Count(string(content), "STREET") != 2 || strings.Contains(string(content), "Street") {
+				t.Errorf("fallback copy was not updated : %s", content)
+			}
+		}
+	}
+}
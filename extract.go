@@ -1,20 +1,18 @@
 package mydocx
 
 import (
-	"archive/zip"
-	"encoding/xml"
 	"fmt"
-	"io"
 	"os"
-
-	"bytes"
+	"strings"
 )
 
 // Extract text content from docx file for external processing.
 // Returns a map from the container name (eg : word/footer1.xml) to a list of text contained in its paragraphs.
+// The paragraphs of a text box are listed just after the paragraph the text box is anchored in.
 // This function is thread-safe.
 // The verbose flag can be set to true to display information about the containers extracted.
-func ExtractText(sourceFilePath string) (map[string][]string, error) {
+// Options can prefix paragraphs with their list labels (see WithNumbering), or extract field instructions instead of field results (see WithFieldCodes).
+func ExtractText(sourceFilePath string, opts ...Option) (map[string][]string, error) {
 	if VERBOSE {
 		fmt.Printf("Extracting text from %s\n", sourceFilePath)
 	}
@@ -22,7 +20,7 @@ func ExtractText(sourceFilePath string) (map[string][]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ExtractTextBytes(data)
+	return ExtractTextBytes(data, opts...)
 }
 
 // Same as ExtractText, but takes a byte array as input.
@@ -30,20 +28,26 @@ func ExtractText(sourceFilePath string) (map[string][]string, error) {
 // Returns a map from the container name (eg : word/footer1.xml) to a list of text contained in its paragraphs.
 // This function is thread-safe.
 // The verbose flag can be set to true to display information about the containers extracted.
-func ExtractTextBytes(sourceBytes []byte) (map[string][]string, error) {
+// Options can prefix paragraphs with their list labels (see WithNumbering), or extract field instructions instead of field results (see WithFieldCodes).
+func ExtractTextBytes(sourceBytes []byte, opts ...Option) (map[string][]string, error) {
 
-	docxFile, err := zip.NewReader(bytes.NewReader(sourceBytes), int64(len(sourceBytes)))
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open docx file: %v", err)
 	}
 
 	// no need to close, since byte buffer
-	// defer docxFile.Close()
+
+	nb, err := conf.numberingOf(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load numbering: %v", err)
+	}
 
 	result := make(map[string][]string)
 
-	for _, file := range docxFile.File {
-		if containerPattern.MatchString(file.Name) {
+	for _, file := range pkg.zr.File {
+		if pkg.selected(file.Name, conf.parts) {
 			if VERBOSE {
 				fmt.Printf("Extracting from %s\n", file.Name)
 			}
@@ -52,8 +56,7 @@ func ExtractTextBytes(sourceBytes []byte) (map[string][]string, error) {
 				return nil, fmt.Errorf("failed to read document.xml: %v", err)
 			}
 			// launch actual extraction
-			dec := xml.NewDecoder(bytes.NewReader(documentContent))
-			result[file.Name], err = extractParagraphs(dec)
+			result[file.Name], err = extractParagraphsView(documentContent, acceptedView, nb, conf.fieldCodes)
 			if err != nil {
 				return result, fmt.Errorf("failed to extract text from %s : %v", file.Name, err)
 			}
@@ -69,7 +72,8 @@ func ExtractTextBytes(sourceBytes []byte) (map[string][]string, error) {
 // This function treats the document as if all changes were rejected - insertions are ignored, deletions are ignored.
 // This function is thread-safe.
 // The verbose flag can be set to true to display information about the containers extracted.
-func ExtractOriginalText(sourceFilePath string) (map[string][]string, error) {
+// Options can prefix paragraphs with their list labels (see WithNumbering), or extract field instructions instead of field results (see WithFieldCodes).
+func ExtractOriginalText(sourceFilePath string, opts ...Option) (map[string][]string, error) {
 	if VERBOSE {
 		fmt.Printf("Extracting original text from %s\n", sourceFilePath)
 	}
@@ -77,7 +81,7 @@ func ExtractOriginalText(sourceFilePath string) (map[string][]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ExtractOriginalTextBytes(data)
+	return ExtractOriginalTextBytes(data, opts...)
 }
 
 // Same as ExtractOriginalText, but takes a byte array as input.
@@ -87,17 +91,24 @@ func ExtractOriginalText(sourceFilePath string) (map[string][]string, error) {
 // This function treats the document as if all changes were rejected - insertions are ignored, deletions are ignored.
 // This function is thread-safe.
 // The verbose flag can be set to true to display information about the containers extracted.
-func ExtractOriginalTextBytes(sourceBytes []byte) (map[string][]string, error) {
+// Options can prefix paragraphs with their list labels (see WithNumbering), or extract field instructions instead of field results (see WithFieldCodes).
+func ExtractOriginalTextBytes(sourceBytes []byte, opts ...Option) (map[string][]string, error) {
 
-	docxFile, err := zip.NewReader(bytes.NewReader(sourceBytes), int64(len(sourceBytes)))
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open docx file: %v", err)
 	}
 
+	nb, err := conf.numberingOf(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load numbering: %v", err)
+	}
+
 	result := make(map[string][]string)
 
-	for _, file := range docxFile.File {
-		if containerPattern.MatchString(file.Name) {
+	for _, file := range pkg.zr.File {
+		if pkg.selected(file.Name, conf.parts) {
 			if VERBOSE {
 				fmt.Printf("Extracting original text from %s\n", file.Name)
 			}
@@ -106,8 +117,7 @@ func ExtractOriginalTextBytes(sourceBytes []byte) (map[string][]string, error) {
 				return nil, fmt.Errorf("failed to read document.xml: %v", err)
 			}
 			// launch actual extraction
-			dec := xml.NewDecoder(bytes.NewReader(documentContent))
-			result[file.Name], err = extractOriginalParagraphs(dec)
+			result[file.Name], err = extractParagraphsView(documentContent, originalView, nb, conf.fieldCodes)
 			if err != nil {
 				return result, fmt.Errorf("failed to extract original text from %s : %v", file.Name, err)
 			}
@@ -118,242 +128,104 @@ func ExtractOriginalTextBytes(sourceBytes []byte) (map[string][]string, error) {
 	return result, nil
 }
 
-// Extract paragraphs text from container content.
-func extractParagraphs(dec *xml.Decoder) (res []string, err error) {
-	var tt string
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "p" && t.Name.Space == NAMESPACE {
-				tt, err = extractRuns(dec)
-				if debugflag {
-					fmt.Printf("Captured text : %q\n", tt)
-				}
-				if err == io.EOF {
-					return res, nil
-				}
-				if err != nil {
-					return nil, err
-				}
-				res = append(res, tt)
-			}
-		}
-	}
-	return res, err
-}
+// Selects how tracked changes are rendered when collecting the text of a paragraph.
+type textView int
 
-// Extract text from the runs in a given paragraph.
-func extractRuns(dec *xml.Decoder) (tt string, err error) {
+const (
+	acceptedView textView = iota // as if all changes were accepted : insertions are included, deletions are ignored
+	originalView                 // as if all changes were rejected : insertions are ignored, deletions are restored
+)
 
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				tt = tt + extractText(dec)
-				if err != nil {
-					break
-				}
-			}
-		case xml.EndElement:
-			if t.Name.Local == "p" && t.Name.Space == NAMESPACE {
-				return tt, err
-			}
-		}
+// Load the numbering of the package if list labels are requested, else return nil.
+func (c *config) numberingOf(pkg *docxPackage) (*numbering, error) {
+	if !c.numbering {
+		return nil, nil
 	}
-	return tt, err
+	return pkg.numbering()
 }
 
-// Extact text exactly as if all changes have been accepetd. Deletions are ingored, insertions are included.
-func extractText(dec *xml.Decoder) string {
-	var tt = ""
-	var inDeletion = false
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "del" && t.Name.Space == NAMESPACE {
-				inDeletion = true
-			} else if t.Name.Local == "t" && t.Name.Space == NAMESPACE && !inDeletion {
-				cdt, err := dec.Token()
-				if err != nil {
-					break
-				}
-				if data, ok := cdt.(xml.CharData); ok {
-					tt = tt + string(data)
-				}
-			} else if t.Name.Local == "delText" && t.Name.Space == NAMESPACE {
-				// Skip deleted text content entirely
-				for {
-					tok, err := dec.Token()
-					if err != nil {
-						break
-					}
-					if endEl, ok := tok.(xml.EndElement); ok && endEl.Name.Local == "delText" && endEl.Name.Space == NAMESPACE {
-						break
-					}
-				}
-			}
-		case xml.EndElement:
-			if t.Name.Local == "del" && t.Name.Space == NAMESPACE {
-				inDeletion = false
-			} else if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				return tt
-			}
-		}
+// Extract paragraphs text from container content, using the provided view of the tracked changes.
+// Paragraphs nested in text boxes are extracted just after their anchor paragraph.
+// Text boxes duplicated in alternate content fallbacks are only extracted once.
+// If the numbering is not nil, paragraphs are prefixed with their list label.
+// If codes is set, field instructions are extracted instead of field results.
+func extractParagraphsView(content []byte, view textView, nb *numbering, codes bool) (res []string, err error) {
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
 	}
-	return tt
-}
-
-// Extract original paragraphs text from container content, ignoring all revisions.
-func extractOriginalParagraphs(dec *xml.Decoder) (res []string, err error) {
-	var tt string
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "p" && t.Name.Space == NAMESPACE {
-				// Check if this paragraph is an insertion (should be ignored in original text)
-				isInsertedParagraph := false
-				tt, isInsertedParagraph, err = extractOriginalRunsFromParagraph(dec)
-				if err == io.EOF {
-					return res, nil
-				}
-				if err != nil {
-					return nil, err
-				}
-				// Only include paragraphs that are not insertions
-				if !isInsertedParagraph {
-					if debugflag {
-						fmt.Printf("Captured original text : %q\n", tt)
-					}
-					res = append(res, tt)
-				} else if debugflag {
-					fmt.Printf("Skipped inserted paragraph : %q\n", tt)
-				}
-			}
-		}
+	if nb != nil {
+		nb.reset()
 	}
-	return res, err
-}
-
-// Extract original text from the runs in a given paragraph, checking if paragraph is inserted.
-func extractOriginalRunsFromParagraph(dec *xml.Decoder) (tt string, isInsertedParagraph bool, err error) {
-	isInsertedParagraph = false
-
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "pPr" && t.Name.Space == NAMESPACE {
-				// Check paragraph properties for insertion markers
-				isInsertedParagraph = checkParagraphPropertiesForInsertion(dec)
-			} else if t.Name.Local == "ins" && t.Name.Space == NAMESPACE {
-				// Skip entire insertion blocks at paragraph level
-				for {
-					tok, err := dec.Token()
-					if err != nil {
-						break
-					}
-					if endEl, ok := tok.(xml.EndElement); ok && endEl.Name.Local == "ins" && endEl.Name.Space == NAMESPACE {
-						break
-					}
-				}
-			} else if t.Name.Local == "del" && t.Name.Space == NAMESPACE {
-				// Process deletion blocks to restore original text
-				tt = tt + extractOriginalTextFromDeletion(dec)
-			} else if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				tt = tt + extractOriginalTextFromRun(dec)
-				if err != nil {
-					break
-				}
-			}
-		case xml.EndElement:
-			if t.Name.Local == "p" && t.Name.Space == NAMESPACE {
-				return tt, isInsertedParagraph, err
-			}
+	fc := new(fieldCodes)
+	for _, p := range root.paragraphs() {
+		tt := paragraphText(p, view)
+		if codes {
+			tt = fc.paragraphText(p, view)
 		}
-	}
-	return tt, isInsertedParagraph, err
-}
-
-// Extract original text from a deletion block at paragraph level
-func extractOriginalTextFromDeletion(dec *xml.Decoder) string {
-	var tt = ""
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				tt = tt + extractOriginalTextFromRun(dec)
-			}
-		case xml.EndElement:
-			if t.Name.Local == "del" && t.Name.Space == NAMESPACE {
-				return tt
+		if nb != nil {
+			if label, suffix := nb.next(p); label != "" {
+				tt = label + suffix + tt
 			}
 		}
+		if debugflag {
+			fmt.Printf("Captured text : %q\n", tt)
+		}
+		res = append(res, tt)
 	}
-	return tt
+	return res, nil
 }
 
-// Check paragraph properties for insertion markers that indicate the entire paragraph was inserted.
-// Note: <w:ins> in paragraph properties (pPr/rPr) typically indicates insertion formatting,
-// not that the paragraph itself was inserted. Actual paragraph insertions are usually
-// at the paragraph content level, not in properties.
-func checkParagraphPropertiesForInsertion(dec *xml.Decoder) bool {
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			// Only consider this an inserted paragraph if we find specific markers
-			// that indicate the paragraph itself (not just its formatting) was inserted.
-			// In most cases, <w:ins> in pPr/rPr is just formatting, not content insertion.
-		case xml.EndElement:
-			if t.Name.Local == "pPr" && t.Name.Space == NAMESPACE {
-				return false
-			}
-		}
+// Get the text of a paragraph, using the provided view of the tracked changes.
+func paragraphText(p *xnode, view textView) string {
+	var sb strings.Builder
+	for _, t := range paragraphTexts(p, view) {
+		sb.WriteString(t.textContent())
 	}
-	return false
+	return sb.String()
 }
 
-// Extract original text from a run, treating document as if all changes were rejected.
-// Insertions are ignored, deletions (delText) are included to restore the original text.
-func extractOriginalTextFromRun(dec *xml.Decoder) string {
-	var tt = ""
-	var inInsertion = false
-	for tok, err := dec.Token(); err == nil; tok, err = dec.Token() {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "ins" && t.Name.Space == NAMESPACE {
-				inInsertion = true
-				// Skip entire insertion block
-				for {
-					tok, err := dec.Token()
-					if err != nil {
-						break
-					}
-					if endEl, ok := tok.(xml.EndElement); ok && endEl.Name.Local == "ins" && endEl.Name.Space == NAMESPACE {
-						break
-					}
-				}
-			} else if t.Name.Local == "t" && t.Name.Space == NAMESPACE && !inInsertion {
-				cdt, err := dec.Token()
-				if err != nil {
-					break
-				}
-				if data, ok := cdt.(xml.CharData); ok {
-					tt = tt + string(data)
-				}
-			} else if t.Name.Local == "delText" && t.Name.Space == NAMESPACE {
-				// Include deleted text content to restore original text
-				cdt, err := dec.Token()
-				if err != nil {
-					break
-				}
-				if data, ok := cdt.(xml.CharData); ok {
-					tt = tt + string(data)
-				}
-			}
-		case xml.EndElement:
-			if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				return tt
+// Get the text elements (w:t, and w:delText for the original view) that make up the text of a paragraph, in document order.
+// Text of nested paragraphs (text boxes) and of alternate content fallbacks is not part of the paragraph.
+// Field instructions are not part of the text, but field results are.
+func paragraphTexts(p *xnode, view textView) (res []*xnode) {
+	var fields []bool // fields opened in the paragraph, true while reading their instruction
+	inCode := func() bool {
+		for _, code := range fields {
+			if code {
+				return true
 			}
 		}
-	}
-	return tt
+		return false
+	}
+	p.walk(func(n *xnode) bool {
+		switch {
+		case n == p:
+			return true
+		case n.is("p"), n.isFallback():
+			return false
+		case view == acceptedView && n.is("del"), view == originalView && n.is("ins"):
+			return false
+		case n.is("fldChar"):
+			switch attrOf(n, "fldCharType") {
+			case "begin":
+				fields = append(fields, true)
+			case "separate":
+				if len(fields) > 0 {
+					fields[len(fields)-1] = false
+				}
+			case "end":
+				if len(fields) > 0 {
+					fields = fields[:len(fields)-1]
+				}
+			}
+		case n.is("t"), view == originalView && n.is("delText"):
+			if n.ancestor("r") != nil && !inCode() {
+				res = append(res, n)
+			}
+			return false
+		}
+		return true
+	})
+	return res
 }
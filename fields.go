@@ -0,0 +1,611 @@
+package mydocx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field describes a Word field (eg : MERGEFIELD, DATE, PAGE, REF ...) found in a document.
+type Field struct {
+	// Name of the container where the field was found (eg : word/document.xml)
+	Container string
+	// Field instruction, as displayed by Word when showing field codes (eg : MERGEFIELD Name \* MERGEFORMAT)
+	Instruction string
+	// Current (cached) result of the field, as computed when Word last updated it.
+	Result string
+}
+
+// field is a field of a container tree, either simple (w:fldSimple) or complex (w:fldChar and w:instrText runs).
+type field struct {
+	simple               *xnode   // w:fldSimple element, nil for complex fields
+	begin, separate, end *xnode   // w:fldChar elements of complex fields. Separate may be nil, end is nil if the field is not closed.
+	codes                []*xnode // w:instrText elements of the instruction, excluding those of nested fields
+	codeTexts            []*xnode // w:t elements found in the instruction, ie : the results of nested fields
+	results              []*xnode // w:t elements of the result, including those of nested fields
+	parent               *field   // enclosing field, nil for top level fields
+}
+
+// Find the fields of a tree, in document order. Nested fields are listed after their enclosing field.
+// Deleted runs and alternate content fallbacks are ignored.
+func findFields(root *xnode) (res []*field) {
+	var stack []*field // open complex fields
+	root.walk(func(n *xnode) bool {
+		var top *field
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch {
+		case n.isFallback(), n.is("del"):
+			return false
+		case n.is("fldSimple"):
+			f := &field{simple: n, parent: top}
+			for _, t := range n.findAll("t") {
+				if t.ancestor("r") != nil {
+					f.results = append(f.results, t)
+				}
+			}
+			res = append(res, f)
+			addFieldText(stack, f.results...)
+			return false
+		case n.is("fldChar"):
+			switch attrOf(n, "fldCharType") {
+			case "begin":
+				f := &field{begin: n, parent: top}
+				res = append(res, f)
+				stack = append(stack, f)
+			case "separate":
+				if top != nil {
+					top.separate = n
+				}
+			case "end":
+				if top != nil {
+					top.end = n
+					stack = stack[:len(stack)-1]
+				}
+			}
+		case n.is("instrText"):
+			if top != nil && top.separate == nil {
+				top.codes = append(top.codes, n)
+			}
+			return false
+		case n.is("t"):
+			if n.ancestor("r") != nil {
+				addFieldText(stack, n)
+			}
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+// Record text elements in the open fields : they are part of the result of the fields whose instruction is complete,
+// and part of the instruction of the innermost field still reading its instruction.
+func addFieldText(stack []*field, texts ...*xnode) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		f := stack[i]
+		if f.separate == nil {
+			f.codeTexts = append(f.codeTexts, texts...)
+			return
+		}
+		f.results = append(f.results, texts...)
+	}
+}
+
+// Get the instruction of a field, without the instructions of nested fields.
+func (f *field) instruction() string {
+	if f.simple != nil {
+		return attrOf(f.simple, "instr")
+	}
+	var sb strings.Builder
+	for _, c := range f.codes {
+		sb.WriteString(c.textContent())
+	}
+	return sb.String()
+}
+
+// Get the current result of a field.
+func (f *field) result() string {
+	var sb strings.Builder
+	for _, t := range f.results {
+		sb.WriteString(t.textContent())
+	}
+	return sb.String()
+}
+
+// Check if the field is complete : simple, or complex with a begin and an end.
+func (f *field) closed() bool {
+	return f.simple != nil || f.end != nil
+}
+
+// Check if the field is nested in another field.
+func (f *field) within(other *field) bool {
+	for p := f.parent; p != nil; p = p.parent {
+		if p == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the runs (or the w:fldSimple element) that start and end the field.
+func (f *field) bounds() (first, last *xnode) {
+	if f.simple != nil {
+		return f.simple, f.simple
+	}
+	first, last = f.begin.ancestor("r"), f.begin.ancestor("r")
+	if f.end != nil {
+		last = f.end.ancestor("r")
+	}
+	return first, last
+}
+
+// Get the run properties of the run that starts the field, or an empty string.
+func (f *field) runProperties() string {
+	if f.simple != nil {
+		return ""
+	}
+	if r := f.begin.ancestor("r"); r != nil && r.child("rPr") != nil {
+		return string(r.child("rPr").bytes())
+	}
+	return ""
+}
+
+// Replace the result of a field with the provided text.
+// The text is saved in the first text element of the result, other text elements are emptied.
+// If the field has no result yet, a result is created, formatted as the start of the field.
+func (f *field) setResult(text string) {
+	if f.simple != nil {
+		setRunsText(f.simple, nil, text)
+		return
+	}
+	if len(f.results) > 0 {
+		for _, t := range f.results[1:] {
+			t.setText("")
+		}
+		setTextLines(f.results[0], text)
+		return
+	}
+	if f.end == nil {
+		return
+	}
+	fragment := `<w:r>` + f.runProperties() + `<w:t xml:space="preserve"></w:t></w:r>`
+	if f.separate == nil {
+		fragment = `<w:r><w:fldChar w:fldCharType="separate"/></w:r>` + fragment
+	}
+	nodes, err := parseFragment(fragment)
+	if err != nil {
+		panic("invalid field result fragment : " + err.Error()) // should never happen
+	}
+	if f.separate != nil {
+		f.separate.ancestor("r").insertAfter(nodes...)
+	} else {
+		f.end.ancestor("r").insertBefore(nodes...)
+	}
+	f.results = nodes[len(nodes)-1].findAll("t")
+	setTextLines(f.results[0], text)
+}
+
+// Remove the structure of a field, and of the fields nested in it, keeping only the text of its result as plain runs.
+func (f *field) unwrap(all []*field) {
+	for _, d := range all {
+		if d != f && !d.within(f) {
+			continue
+		}
+		if d.simple != nil {
+			d.simple.insertBefore(d.simple.children...)
+			d.simple.remove()
+			continue
+		}
+		for _, n := range append(append([]*xnode{d.begin, d.separate, d.end}, d.codes...), d.codeTexts...) {
+			if n != nil {
+				n.remove()
+			}
+		}
+	}
+}
+
+// fieldCodes renders the text of paragraphs showing the field instructions instead of their results,
+// as Word does when field codes are displayed : { MERGEFIELD Name }.
+// Paragraphs must be submitted in document order, since field results may span several paragraphs.
+type fieldCodes struct {
+	open []struct{ result, shown bool } // open complex fields : reading their result, braces were displayed
+}
+
+// Check if the text currently belongs to a field result, and is hidden.
+func (fc *fieldCodes) hidden() bool {
+	for _, f := range fc.open {
+		if f.result {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the text of a paragraph, using the provided view of the tracked changes, with field codes instead of field results.
+func (fc *fieldCodes) paragraphText(p *xnode, view textView) string {
+	var sb strings.Builder
+	p.walk(func(n *xnode) bool {
+		switch {
+		case n == p:
+			return true
+		case n.is("p"), n.isFallback():
+			return false
+		case view == acceptedView && n.is("del"), view == originalView && n.is("ins"):
+			return false
+		case n.is("fldSimple"):
+			if !fc.hidden() {
+				sb.WriteString("{" + attrOf(n, "instr") + "}")
+			}
+			return false
+		case n.is("fldChar"):
+			switch attrOf(n, "fldCharType") {
+			case "begin":
+				shown := !fc.hidden()
+				if shown {
+					sb.WriteString("{")
+				}
+				fc.open = append(fc.open, struct{ result, shown bool }{false, shown})
+			case "separate":
+				if len(fc.open) > 0 {
+					fc.open[len(fc.open)-1].result = true
+				}
+			case "end":
+				if len(fc.open) > 0 {
+					if fc.open[len(fc.open)-1].shown {
+						sb.WriteString("}")
+					}
+					fc.open = fc.open[:len(fc.open)-1]
+				}
+			}
+		case n.is("t"), n.is("instrText"), view == originalView && (n.is("delText") || n.is("delInstrText")):
+			if n.ancestor("r") != nil && !fc.hidden() {
+				sb.WriteString(n.textContent())
+			}
+			return false
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// fieldInstruction is a parsed field instruction, such as : MERGEFIELD "Due date" \@ "dd/MM/yyyy" \* MERGEFORMAT
+type fieldInstruction struct {
+	kind     string        // field type, upper case (eg : MERGEFIELD)
+	args     []string      // arguments, unquoted
+	switches []fieldSwitch // switches, in order
+}
+
+// fieldSwitch is a switch of a field instruction, such as \@ "dd/MM/yyyy" or \h
+type fieldSwitch struct {
+	name string // switch character(s), without the backslash (eg : @, *, h)
+	arg  string // argument, unquoted, empty for flags
+}
+
+// Switches that expect an argument. Other switches are flags.
+var fieldSwitchArgs = map[string]bool{"@": true, "*": true, "#": true, "b": true, "f": true, "l": true, "o": true}
+
+// Parse a field instruction.
+func parseInstruction(instr string) (fi fieldInstruction) {
+	tokens := splitInstruction(instr)
+	if len(tokens) == 0 {
+		return fi
+	}
+	fi.kind = strings.ToUpper(tokens[0])
+	for i := 1; i < len(tokens); i++ {
+		tk := tokens[i]
+		if !strings.HasPrefix(tk, `\`) {
+			fi.args = append(fi.args, tk)
+			continue
+		}
+		sw := fieldSwitch{name: tk[1:]}
+		if fieldSwitchArgs[sw.name] && i+1 < len(tokens) {
+			i++
+			sw.arg = tokens[i]
+		}
+		fi.switches = append(fi.switches, sw)
+	}
+	return fi
+}
+
+// Split a field instruction into tokens, separated by spaces. Double quotes group and are removed.
+// Within quotes, a backslash escapes the next character.
+func splitInstruction(instr string) (tokens []string) {
+	var sb strings.Builder
+	quoted, pending := false, false
+	for i := 0; i < len(instr); i++ {
+		c := instr[i]
+		switch {
+		case c == '"':
+			quoted, pending = !quoted, true
+		case quoted && c == '\\' && i+1 < len(instr) && (instr[i+1] == '"' || instr[i+1] == '\\'):
+			i++
+			sb.WriteByte(instr[i])
+		case !quoted && (c == ' ' || c == '\t' || c == '\r' || c == '\n'):
+			if pending || sb.Len() > 0 {
+				tokens = append(tokens, sb.String())
+			}
+			sb.Reset()
+			pending = false
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if pending || sb.Len() > 0 {
+		tokens = append(tokens, sb.String())
+	}
+	return tokens
+}
+
+// Get the argument of the first switch with the provided name.
+func (fi fieldInstruction) switchArg(name string) (string, bool) {
+	for _, sw := range fi.switches {
+		if sw.name == name {
+			return sw.arg, true
+		}
+	}
+	return "", false
+}
+
+// List the fields of the docx file, with their instruction and their current result,
+// in reading order of the containers, then document order. Nested fields are listed after their enclosing field.
+func ListFields(sourceFilePath string, opts ...Option) ([]Field, error) {
+	return fromFile(sourceFilePath, ListFieldsBytes, opts)
+}
+
+// Same as ListFields, but takes a byte array as input.
+func ListFieldsBytes(sourceBytes []byte, opts ...Option) ([]Field, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	containers, err := pkg.containers(conf.parts)
+	if err != nil {
+		return nil, err
+	}
+	var res []Field
+	for _, c := range containers {
+		content, err := pkg.read(c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", c.Name, err)
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", c.Name, err)
+		}
+		for _, f := range findFields(root) {
+			res = append(res, Field{Container: c.Name, Instruction: strings.TrimSpace(f.instruction()), Result: f.result()})
+		}
+	}
+	return res, nil
+}
+
+// Update the results of the simple fields of the docx file :
+//   - MERGEFIELD, from the provided data (a map with string keys, or a struct, see FillContentControls),
+//   - DOCPROPERTY, from the provided data, or else from the document properties (Title, Author, custom properties, ...),
+//   - DATE and TIME, from the current time,
+//   - REF, from the text of the referenced bookmark.
+//
+// Date (\@), numeric (\#) and format (\*) switches are applied. Other fields, and fields whose value is unknown, are left unchanged.
+// The field structure is preserved, so that Word can update the fields again later.
+// If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
+func UpdateFields(sourceFilePath string, data any, targetFilePath string, opts ...Option) error {
+	return modifyFile(sourceFilePath, targetFilePath, "update fields", func(in []byte) ([]byte, error) {
+		return UpdateFieldsBytes(in, data, opts...)
+	})
+}
+
+// Same as UpdateFields, but takes a byte array as input and returns the modified docx as a byte array.
+func UpdateFieldsBytes(sourceBytes []byte, data any, opts ...Option) ([]byte, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input bytes: %v", err)
+	}
+	ev, err := pkg.fieldEvaluator(data)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.rewrite(conf.parts, func(_ string, root *xnode) error {
+		for _, f := range findFields(root) {
+			if !f.closed() {
+				continue
+			}
+			fi := parseInstruction(f.instruction())
+			if value, ok := ev.evaluate(fi); ok {
+				if VERBOSE {
+					fmt.Printf("Updating field %q : %q\n", f.instruction(), value)
+				}
+				f.setResult(value)
+			}
+		}
+		return nil
+	})
+}
+
+// fieldEvaluator computes the values of the fields of a document.
+type fieldEvaluator struct {
+	data       any               // user provided data
+	properties map[string]string // document properties
+	bookmarks  map[string]string // text of the bookmarks
+	now        time.Time         // time used for DATE and TIME fields
+}
+
+// Prepare the evaluation of the fields of the package.
+func (pkg *docxPackage) fieldEvaluator(data any) (*fieldEvaluator, error) {
+	ev := &fieldEvaluator{data: data, now: time.Now(), bookmarks: make(map[string]string)}
+	var err error
+	if ev.properties, err = pkg.properties(); err != nil {
+		return nil, fmt.Errorf("failed to read document properties: %v", err)
+	}
+	for _, name := range pkg.names(AllParts) {
+		content, err := pkg.read(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		order := newDocOrder(root)
+		for _, b := range findBookmarks(root) {
+			ev.bookmarks[b.name] = order.bookmarkText(b)
+		}
+	}
+	return ev, nil
+}
+
+// Compute the value of a field. Returns false if the field is not supported, or if its value is unknown.
+func (ev *fieldEvaluator) evaluate(fi fieldInstruction) (string, bool) {
+	var value any
+	switch {
+	case fi.kind == "MERGEFIELD" && len(fi.args) > 0:
+		v, ok := lookupValue(ev.data, fi.args[0])
+		if !ok {
+			return "", false
+		}
+		value = v
+	case fi.kind == "DOCPROPERTY" && len(fi.args) > 0:
+		v, ok := lookupValue(ev.data, fi.args[0])
+		if !ok {
+			if v, ok = lookupFold(ev.properties, fi.args[0]); !ok {
+				return "", false
+			}
+		}
+		value = v
+	case fi.kind == "DATE":
+		if _, ok := fi.switchArg("@"); !ok {
+			return formatWordDate(ev.now, "M/d/yyyy"), true
+		}
+		value = ev.now
+	case fi.kind == "TIME":
+		if _, ok := fi.switchArg("@"); !ok {
+			return formatWordDate(ev.now, "h:mm AM/PM"), true
+		}
+		value = ev.now
+	case fi.kind == "REF" && len(fi.args) > 0:
+		v, ok := ev.bookmarks[fi.args[0]]
+		if !ok {
+			return "", false
+		}
+		value = v
+	default:
+		return "", false
+	}
+	return formatFieldValue(value, fi), true
+}
+
+// Format the value of a field, applying its switches :
+// date (\@ "dd/MM/yyyy"), numeric (\# "#,##0.00") and format (\* Upper, \* roman, ...) switches,
+// then the text to insert before (\b) and after (\f) a non empty value.
+func formatFieldValue(value any, fi fieldInstruction) string {
+	text := toText(value)
+	if picture, ok := fi.switchArg("@"); ok {
+		if t, ok := toDate(value); ok {
+			text = formatWordDate(t, picture)
+		}
+	}
+	if picture, ok := fi.switchArg("#"); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+			text = formatWordNumber(f, picture)
+		}
+	}
+	for _, sw := range fi.switches {
+		if sw.name == "*" {
+			text = applyFormatSwitch(text, sw.arg)
+		}
+	}
+	if text != "" {
+		before, _ := fi.switchArg("b")
+		after, _ := fi.switchArg("f")
+		text = before + text + after
+	}
+	return text
+}
+
+// Lookup a key in a map of strings, ignoring case.
+func lookupFold(m map[string]string, key string) (string, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Document properties, as named by the DOCPROPERTY field, mapped to the corresponding elements of docProps/core.xml and docProps/app.xml.
+var propertyNames = map[string]string{
+	"title": "Title", "subject": "Subject", "creator": "Author", "keywords": "Keywords", "description": "Comments",
+	"lastModifiedBy": "LastSavedBy", "revision": "RevisionNumber", "category": "Category",
+	"created": "CreateTime", "modified": "LastSavedTime", "lastPrinted": "LastPrinted",
+	"Company": "Company", "Manager": "Manager", "Template": "Template", "Pages": "Pages", "Words": "Words", "Characters": "Characters",
+}
+
+// Relationship types of the document properties parts.
+const (
+	corePropertiesRelType     = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	extendedPropertiesRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	customPropertiesRelType   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties"
+)
+
+// Get the properties of the document : core and extended properties, with their DOCPROPERTY name, and custom properties.
+// The properties parts are found from the relationships of the package.
+func (pkg *docxPackage) properties() (map[string]string, error) {
+	res := make(map[string]string)
+	read := func(relType string, conventional string) (*xnode, error) {
+		name, err := pkg.relationTarget("", relType, conventional)
+		if err != nil {
+			return nil, err
+		}
+		content, err := pkg.read(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		if content == nil {
+			return nil, nil
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		return root, nil
+	}
+	for _, rel := range [][2]string{{corePropertiesRelType, "docProps/core.xml"}, {extendedPropertiesRelType, "docProps/app.xml"}} {
+		root, err := read(rel[0], rel[1])
+		if err != nil {
+			return nil, err
+		}
+		if root == nil {
+			continue
+		}
+		for _, props := range root.children {
+			for _, c := range props.children {
+				if p, ok := propertyNames[c.name.Local]; ok {
+					res[p] = c.textContent()
+				}
+			}
+		}
+	}
+	root, err := read(customPropertiesRelType, "docProps/custom.xml")
+	if err != nil || root == nil {
+		return res, err
+	}
+	root.walk(func(c *xnode) bool {
+		if c.name.Local != "property" {
+			return true
+		}
+		for _, a := range c.attr {
+			if a.Name.Local == "name" {
+				res[a.Value] = c.textContent()
+			}
+		}
+		return false
+	})
+	return res, nil
+}
@@ -0,0 +1,243 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Build a complex field, with its instruction and its result, each in a run.
+func testField(instr, result string) string {
+	return `<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve">` + instr + `</w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>` + result + `</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>`
+}
+
+// fields : merge field between plain text, simple field, date, reference to a bookmark, and a merge field nested in an IF field
+var testFields = `<w:p><w:r><w:t xml:space="preserve">Dear </w:t></w:r>` + testField(" MERGEFIELD FirstName ", "«FirstName»") + `<w:r><w:t>, welcome.</w:t></w:r></w:p>` +
+	`<w:p><w:fldSimple w:instr=" DOCPROPERTY Title "><w:r><w:t>Old title</w:t></w:r></w:fldSimple></w:p>` +
+	`<w:p><w:r><w:t xml:space="preserve">Date: </w:t></w:r>` + testField(` DATE \@ "yyyy-MM-dd" `, "2000-01-01") + `</w:p>` +
+	`<w:p><w:bookmarkStart w:id="0" w:name="amount"/><w:r><w:t>42 EUR</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+	`<w:p><w:r><w:t xml:space="preserve">Total: </w:t></w:r>` + testField(` REF amount \h `, "0") + `</w:p>` +
+	`<w:p><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> IF </w:instrText></w:r>` + testField(" MERGEFIELD Vip ", "no") +
+	`<w:r><w:instrText xml:space="preserve"> = "yes" "Gold" "Std" </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>Std</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`
+
+const testCoreProperties = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Contract</dc:title><dc:creator>Jane</dc:creator></cp:coreProperties>`
+
+func TestFields(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testFields),
+		"docProps/core.xml": testCoreProperties,
+	})
+
+	check := func(name string, got, want []string) {
+		t.Helper()
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("%s :\ngot  %q\nwant %q", name, got, want)
+		}
+	}
+
+	pp, err := ExtractTextBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("results", pp["word/document.xml"], []string{"Dear «FirstName», welcome.", "Old title", "Date: 2000-01-01", "42 EUR", "Total: 0", "Std"})
+
+	pp, err = ExtractTextBytes(docx, WithFieldCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := []string{"Dear { MERGEFIELD FirstName }, welcome.", "{ DOCPROPERTY Title }", `Date: { DATE \@ "yyyy-MM-dd" }`, "42 EUR",
+		`Total: { REF amount \h }`, `{ IF { MERGEFIELD Vip } = "yes" "Gold" "Std" }`}
+	check("codes", pp["word/document.xml"], codes)
+
+	ff, err := ListFieldsBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var instr []string
+	for _, f := range ff {
+		instr = append(instr, f.Instruction+"="+f.Result)
+	}
+	check("fields", instr, []string{"MERGEFIELD FirstName=«FirstName»", "DOCPROPERTY Title=Old title", `DATE \@ "yyyy-MM-dd"=2000-01-01`,
+		`REF amount \h=0`, `IF  = "yes" "Gold" "Std"=Std`, "MERGEFIELD Vip=no"})
+
+	// update fields, keeping their structure
+	out, err := UpdateFieldsBytes(docx, map[string]any{"FirstName": "John"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err = ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("updated", pp["word/document.xml"], []string{"Dear John, welcome.", "Contract", "Date: " + time.Now().Format("2006-01-02"), "42 EUR", "Total: 42 EUR", "Std"})
+	pp, err = ExtractTextBytes(out, WithFieldCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("updated codes", pp["word/document.xml"], codes)
+
+	// modifying the text around fields keeps the fields
+	out, err = ModifyTextBytes(docx, func(_, s string) []string {
+		s = strings.ReplaceAll(s, "welcome", "hello")
+		return []string{strings.ReplaceAll(s, "Old title", "Title: Old title")}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err = ExtractTextBytes(out, WithFieldCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("modified codes", pp["word/document.xml"], []string{"Dear { MERGEFIELD FirstName }, hello.", "Title: { DOCPROPERTY Title }", codes[2], codes[3], codes[4], codes[5]})
+
+	// a field with an empty result stays in place when the text before it is modified
+	empty := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(`<w:p><w:r><w:t xml:space="preserve">Dear </w:t></w:r>` + testField(" MERGEFIELD FirstName ", "") + `<w:r><w:t>, welcome.</w:t></w:r></w:p>`),
+	})
+	out, err = ModifyTextBytes(empty, func(_, s string) []string {
+		return []string{strings.ReplaceAll(s, "Dear", "Hello")}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err = ExtractTextBytes(out, WithFieldCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("empty result", pp["word/document.xml"], []string{"Hello { MERGEFIELD FirstName }, welcome."})
+
+	// replacing a field result converts the fields of the paragraph into plain text
+	out, err = ModifyTextBytes(docx, func(_, s string) []string {
+		return []string{strings.ReplaceAll(s, "«FirstName»", "Jane")}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err = ExtractTextBytes(out, WithFieldCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("replaced codes", pp["word/document.xml"], append([]string{"Dear Jane, welcome."}, codes[1:]...))
+}
+
+func TestPropertiesRelationships(t *testing.T) {
+
+	// the core properties are found from the relationships of the package, whatever their name
+	docx := makeDocx(t, map[string]string{
+		contentTypesName: testContentTypes,
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/><Relationship Id="rId2" Type="` + corePropertiesRelType + `" Target="props/core.xml"/></Relationships>`,
+		"word/document.xml": testDocument(testFields),
+		"props/core.xml":    testCoreProperties,
+	})
+	out, err := UpdateFieldsBytes(docx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) < 2 || got[1] != "Contract" {
+		t.Errorf("unexpected title : %q", got)
+	}
+
+	// a malformed properties part is an error
+	docx = makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testFields),
+		"docProps/core.xml": "<cp:coreProperties",
+	})
+	if _, err = UpdateFieldsBytes(docx, nil); err == nil {
+		t.Errorf("missing error for a malformed properties part")
+	}
+}
+
+func TestParseInstruction(t *testing.T) {
+	fi := parseInstruction(` MERGEFIELD "Due date" \@ "dd/MM/yyyy" \* MERGEFORMAT \b "Due: " \h`)
+	if fi.kind != "MERGEFIELD" || len(fi.args) != 1 || fi.args[0] != "Due date" || len(fi.switches) != 4 {
+		t.Fatalf("unexpected instruction : %+v", fi)
+	}
+	if a, _ := fi.switchArg("@"); a != "dd/MM/yyyy" {
+		t.Errorf("unexpected date switch : %q", a)
+	}
+	if a, _ := fi.switchArg("b"); a != "Due: " {
+		t.Errorf("unexpected text before switch : %q", a)
+	}
+	if _, ok := fi.switchArg("h"); !ok {
+		t.Errorf("missing flag")
+	}
+}
+
+func TestMergeFields(t *testing.T) {
+
+	body := `<w:p><w:r><w:t xml:space="preserve">Dear </w:t></w:r>` + testField(` MERGEFIELD FirstName \* Upper `, "«FirstName»") + `<w:r><w:t>,</w:t></w:r></w:p>` +
+		`<w:p><w:fldSimple w:instr=" MERGEFIELD Due \@ &quot;dd MMMM yyyy&quot; "><w:r><w:t>«Due»</w:t></w:r></w:fldSimple></w:p>` +
+		`<w:p>` + testField(` MERGEFIELD Amount \# "#,##0.00" \b "Total: " `, "«Amount»") + `</w:p>` +
+		`<w:p>` + testField(` MERGEFIELD Missing `, "«Missing»") + `</w:p>` +
+		`<w:p><w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve"> IF </w:instrText></w:r>` + testField(" MERGEFIELD Vip ", "«Vip»") +
+		`<w:r><w:instrText xml:space="preserve"> = "yes" "Gold" "Std" </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>Std</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	data := struct {
+		FirstName string
+		Due       time.Time
+		Amount    float64
+		Vip       string
+	}{"John", time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), 1234.5, "yes"}
+
+	out, err := MergeFieldsBytes(docx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out, WithFieldCodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Dear JOHN,", "14 March 2025", "Total: 1,234.50", "{ MERGEFIELD Missing }", `{ IF yes = "yes" "Gold" "Std" }`}
+	if got := pp["word/document.xml"]; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestFieldFormats(t *testing.T) {
+	numbers := []struct {
+		f       float64
+		picture string
+		want    string
+	}{
+		{1234.5, "#,##0.00", "1,234.50"},
+		{1234.5, "0", "1235"},
+		{0.5, "0.##", "0.5"},
+		{-42, "€ #,##0", "€ -42"},
+		{7, "000", "007"},
+	}
+	for _, tt := range numbers {
+		if got := formatWordNumber(tt.f, tt.picture); got != tt.want {
+			t.Errorf("formatWordNumber(%v, %q) = %q, want %q", tt.f, tt.picture, got, tt.want)
+		}
+	}
+	formats := []struct{ text, format, want string }{
+		{"john DOE", "Upper", "JOHN DOE"},
+		{"john DOE", "FirstCap", "John doe"},
+		{"john DOE", "Caps", "John Doe"},
+		{"14", "roman", "xiv"},
+		{"14", "ROMAN", "XIV"},
+		{"3", "alphabetic", "c"},
+		{"21", "Ordinal", "21st"},
+		{"text", "MERGEFORMAT", "text"},
+	}
+	for _, tt := range formats {
+		if got := applyFormatSwitch(tt.text, tt.format); got != tt.want {
+			t.Errorf("applyFormatSwitch(%q, %q) = %q, want %q", tt.text, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,515 @@
+package mydocx
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// register the library of template functions : numbers, currencies, dates, text and data access.
+// The locale aware functions accept an optional last argument, the locale ("en", the default, or "fr").
+// formatNumber, padLeft, padRight and default take the value last instead, so that it can be piped : {{.Total | formatNumber 2}}
+func init() {
+
+	// formatNumber takes a number of decimals, optionally a locale, and a number, and returns the number with thousand separators (1,234.50, or 1 234,50 in french)
+	RegisterTplFunction("formatNumber", tplNumber)
+
+	// currency takes an amount and an ISO 4217 currency code, and returns the formatted amount ($1,234.50, or 1 234,50 € in french)
+	RegisterTplFunction("currency", tplCurrency)
+
+	// percent takes a ratio and a number of decimals, and returns the percentage (0.125 : 12.5%, or 12,5 % in french)
+	RegisterTplFunction("percent", tplPercent)
+
+	// words takes an integer number, and returns it in words (1234 : one thousand two hundred thirty-four, or mille deux cent trente-quatre in french)
+	RegisterTplFunction("words", tplWords)
+
+	// amountWords takes an amount and an ISO 4217 currency code (EUR, USD, GBP or CHF), and returns the amount in words, for cheques and contracts
+	RegisterTplFunction("amountWords", tplAmountWords)
+
+	// now takes no argument and returns the current time, to be formatted with formatDate
+	RegisterTplFunction("now", time.Now)
+
+	// parseDate takes a date string, and optionally its layout (see time.Parse), and returns the date. Default layouts are RFC 3339, 2006-01-02 15:04:05 and 2006-01-02
+	RegisterTplFunction("parseDate", tplParseDate)
+
+	// formatDate takes a date (a time.Time, or a string, see parseDate) and a layout (see time.Format), and returns the formatted date, with month and day names in the locale
+	RegisterTplFunction("formatDate", tplFormatDate)
+
+	// addDays, addMonths and addYears take a date and a number, and return the date moved by that number of days, months or years
+	RegisterTplFunction("addDays", func(date any, n int) (time.Time, error) { return tplAddDate(date, 0, 0, n) })
+	RegisterTplFunction("addMonths", func(date any, n int) (time.Time, error) { return tplAddDate(date, 0, n, 0) })
+	RegisterTplFunction("addYears", func(date any, n int) (time.Time, error) { return tplAddDate(date, n, 0, 0) })
+
+	// daysBetween takes two dates, and returns the number of days from the first to the second
+	RegisterTplFunction("daysBetween", tplDaysBetween)
+
+	// plural takes a count, a singular and a plural form, and returns the form matching the count (in french, 0 and 1 are singular)
+	RegisterTplFunction("plural", tplPlural)
+
+	// upper, lower and title take a value, and return its text in upper case, lower case, or with the first letter of each word in upper case
+	RegisterTplFunction("upper", func(value any) string { return strings.ToUpper(toText(value)) })
+	RegisterTplFunction("lower", func(value any) string { return strings.ToLower(toText(value)) })
+	RegisterTplFunction("title", func(value any) string { return tplTitle(toText(value)) })
+
+	// bold, italic and underline take a value, and return its text in bold, italic or underlined, in new runs inheriting the properties of the paragraph run.
+	// They can be combined : {{bold (italic .Name)}}
+	RegisterTplFunction("bold", func(value any) string { return addFormat(toText(value), 'b') })
+	RegisterTplFunction("italic", func(value any) string { return addFormat(toText(value), 'i') })
+	RegisterTplFunction("underline", func(value any) string { return addFormat(toText(value), 'u') })
+
+	// padLeft and padRight take a width, optionally a padding string (a space by default), and a value, and return the text of the value padded to the width
+	RegisterTplFunction("padLeft", func(width int, args ...any) (string, error) { return tplPad(width, args, true) })
+	RegisterTplFunction("padRight", func(width int, args ...any) (string, error) { return tplPad(width, args, false) })
+
+	// default takes a default value and a value, and returns the default value if the value is empty (nil, zero, empty string, slice or map)
+	RegisterTplFunction("default", tplDefault)
+
+	// get takes a map, a slice or an array, and a key or an index, and returns the element, or an empty string if there is none
+	RegisterTplFunction("get", tplGet)
+}
+
+// localeInfo holds the conventions of a locale.
+type localeInfo struct {
+	thousands, decimal string
+	currencyAfter      bool   // the currency symbol follows the amount
+	percent            string // percent sign, with its leading space if any
+	months, days       []string
+	shortMonths        []string
+	shortDays          []string
+	singularZero       bool                      // 0 takes the singular form
+	words              func(n uint64) string     // integer in words
+	minus              string                    // negative numbers in words
+	units              map[string][4]string      // currency units in words : unit, units, subunit, subunits
+	join               func(a, b string) string  // join the units and the subunits of an amount in words
+	of                 func(units string) string // units of a whole number of millions in words (eg : d'euros), if they differ
+}
+
+// Supported locales.
+var locales = map[string]*localeInfo{
+	"en": {
+		thousands: ",", decimal: ".", percent: "%",
+		months:      []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		shortMonths: []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		days:        []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		shortDays:   []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		words:       englishWords,
+		minus:       "minus ",
+		units: map[string][4]string{
+			"EUR": {"euro", "euros", "cent", "cents"},
+			"USD": {"dollar", "dollars", "cent", "cents"},
+			"GBP": {"pound", "pounds", "penny", "pence"},
+			"CHF": {"franc", "francs", "centime", "centimes"},
+		},
+		join: func(a, b string) string { return a + " and " + b },
+	},
+	"fr": {
+		thousands: "\u00a0", decimal: ",", currencyAfter: true, percent: "\u00a0%", singularZero: true,
+		months:      []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   []string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		words:       frenchWords,
+		minus:       "moins ",
+		units: map[string][4]string{
+			"EUR": {"euro", "euros", "centime", "centimes"},
+			"USD": {"dollar", "dollars", "cent", "cents"},
+			"GBP": {"livre sterling", "livres sterling", "penny", "pence"},
+			"CHF": {"franc suisse", "francs suisses", "centime", "centimes"},
+		},
+		join: func(a, b string) string { return a + " et " + b },
+		of: func(units string) string {
+			if strings.ContainsRune("aeiouy", rune(units[0])) {
+				return "d'" + units
+			}
+			return "de " + units
+		},
+	},
+}
+
+// Get the locale from the optional locale argument of a function (eg : fr, fr-FR or fr_FR). The default locale is en.
+func getLocale(locale []string) (*localeInfo, error) {
+	if len(locale) == 0 || locale[0] == "" {
+		return locales["en"], nil
+	}
+	name := strings.ToLower(locale[0])
+	if i := strings.IndexAny(name, "-_"); i >= 0 {
+		name = name[:i]
+	}
+	if l, ok := locales[name]; ok && len(locale) == 1 {
+		return l, nil
+	}
+	return nil, fmt.Errorf("unsupported locale %q, use en or fr", strings.Join(locale, " "))
+}
+
+// Get the value of a number, or of a string holding a number.
+func toFloat(value any) (float64, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.String()), 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a number : %q", v.String())
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("not a number : %v (%T)", value, value)
+}
+
+// Format a non negative number with the separators of the locale.
+func (l *localeInfo) number(f float64, decimals int) string {
+	integer, fraction, _ := strings.Cut(strconv.FormatFloat(f, 'f', decimals, 64), ".")
+	if fraction != "" {
+		return groupThousands(integer, l.thousands) + l.decimal + fraction
+	}
+	return groupThousands(integer, l.thousands)
+}
+
+// Format a number, with thousand separators and the provided number of decimals.
+func tplNumber(decimals int, args ...any) (string, error) {
+	value, locale, err := pipedValue("formatNumber", args)
+	if err != nil {
+		return "", err
+	}
+	return formatDecimal(value, decimals, locale)
+}
+
+// Format a number with thousand separators in a locale.
+func formatDecimal(value any, decimals int, locale []string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	f = roundTo(f, decimals)
+	if f < 0 {
+		return "-" + l.number(-f, decimals), nil
+	}
+	return l.number(f, decimals), nil
+}
+
+// Round a number to the provided number of decimals, avoiding -0.
+func roundTo(f float64, decimals int) float64 {
+	p := math.Pow10(decimals)
+	return math.Round(f*p)/p + 0
+}
+
+// Symbols of the main currencies.
+var currencySymbols = map[string]string{"EUR": "€", "USD": "$", "GBP": "£", "JPY": "¥"}
+
+// Currencies without decimals.
+var noDecimals = map[string]bool{"JPY": true, "KRW": true}
+
+// Format an amount, with the symbol of its currency, or its code.
+func tplCurrency(value any, code string, locale ...string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	code = strings.ToUpper(code)
+	decimals := 2
+	if noDecimals[code] {
+		decimals = 0
+	}
+	s, err := formatDecimal(value, decimals, locale)
+	if err != nil {
+		return "", err
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	symbol, ok := currencySymbols[code]
+	switch {
+	case l.currencyAfter:
+		if !ok {
+			symbol = code
+		}
+		return sign + s + "\u00a0" + symbol, nil
+	case ok:
+		return sign + symbol + s, nil
+	default:
+		return sign + code + "\u00a0" + s, nil
+	}
+}
+
+// Format a ratio as a percentage.
+func tplPercent(value any, decimals int, locale ...string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	s, err := formatDecimal(f*100, decimals, locale)
+	if err != nil {
+		return "", err
+	}
+	return s + l.percent, nil
+}
+
+// Get an integer in words.
+func tplWords(value any, locale ...string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	if f != math.Trunc(f) || math.Abs(f) >= 1e15 {
+		return "", fmt.Errorf("words expects an integer below 10^15, got %v", value)
+	}
+	if f < 0 {
+		return l.minus + l.words(uint64(-f)), nil
+	}
+	return l.words(uint64(f)), nil
+}
+
+// Get an amount in words, with the units of its currency (eg : one thousand euros and fifty cents).
+func tplAmountWords(value any, code string, locale ...string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	f, err := toFloat(value)
+	if err != nil {
+		return "", err
+	}
+	units, ok := l.units[strings.ToUpper(code)]
+	if !ok {
+		return "", fmt.Errorf("unsupported currency %q for amounts in words, use EUR, USD, GBP or CHF", code)
+	}
+	cents := math.Round(math.Abs(f) * 100)
+	if f < 0 || cents >= 1e17 {
+		return "", fmt.Errorf("amountWords expects a positive amount below 10^15, got %v", value)
+	}
+	n, c := uint64(cents)/100, uint64(cents)%100
+	unit := units[0]
+	switch {
+	case n >= 1000000 && n%1000000 == 0 && l.of != nil:
+		unit = l.of(units[1]) // un million d'euros
+	case n > 1 || (n == 0 && !l.singularZero):
+		unit = units[1]
+	}
+	res := l.words(n) + " " + unit
+	if c == 0 {
+		return res, nil
+	}
+	sub := l.words(c) + " " + units[3]
+	if c == 1 {
+		sub = l.words(c) + " " + units[2]
+	}
+	if n == 0 {
+		return sub, nil
+	}
+	return l.join(res, sub), nil
+}
+
+// Parse a date, with the provided layout, or with the default layouts.
+func tplParseDate(s string, layout ...string) (time.Time, error) {
+	layouts := dateLayouts
+	if len(layout) > 0 {
+		layouts = layout
+	}
+	s = strings.TrimSpace(s)
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse date %q", s)
+}
+
+// Get the date of a time.Time, or of a string (see toDate).
+func toTime(date any) (time.Time, error) {
+	if t, ok := toDate(date); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("not a date : %v (%T)", date, date)
+}
+
+// Markers of the month and day names in a layout, replaced after formatting by their name in the locale.
+var nameMarkers = []struct{ layout, marker string }{
+	{"January", "\x01"}, {"Jan", "\x02"}, {"Monday", "\x03"}, {"Mon", "\x04"},
+}
+
+// Format a date, with the names of the months and days in the locale.
+func tplFormatDate(date any, layout string, locale ...string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	t, err := toTime(date)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range nameMarkers {
+		layout = strings.ReplaceAll(layout, m.layout, m.marker)
+	}
+	return strings.NewReplacer(
+		"\x01", l.months[t.Month()-1], "\x02", l.shortMonths[t.Month()-1],
+		"\x03", l.days[t.Weekday()], "\x04", l.shortDays[t.Weekday()],
+	).Replace(t.Format(layout)), nil
+}
+
+// Move a date by years, months and days.
+func tplAddDate(date any, years, months, days int) (time.Time, error) {
+	t, err := toTime(date)
+	if err != nil {
+		return t, err
+	}
+	return t.AddDate(years, months, days), nil
+}
+
+// Get the number of calendar days from a date to another.
+func tplDaysBetween(from, to any) (int, error) {
+	f, err := toTime(from)
+	if err != nil {
+		return 0, err
+	}
+	t, err := toTime(to)
+	if err != nil {
+		return 0, err
+	}
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) }
+	return int(math.Round(day(t).Sub(day(f)).Hours() / 24)), nil
+}
+
+// Get the singular or the plural form, for a count.
+func tplPlural(count any, singular, pluralForm string, locale ...string) (string, error) {
+	l, err := getLocale(locale)
+	if err != nil {
+		return "", err
+	}
+	f, err := toFloat(count)
+	if err != nil {
+		return "", err
+	}
+	if f == 1 || f == -1 || (l.singularZero && math.Abs(f) < 2) {
+		return singular, nil
+	}
+	return pluralForm, nil
+}
+
+// Put the first letter of each word in upper case.
+func tplTitle(s string) string {
+	var sb strings.Builder
+	start := true
+	for _, r := range s {
+		if start && unicode.IsLetter(r) {
+			r = unicode.ToUpper(r)
+		}
+		start = !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Pad a text, on the left or on the right, to a width in characters, with a padding string repeated as needed.
+func tplPad(width int, args []any, left bool) (string, error) {
+	value, pad, err := pipedValue("padLeft/padRight", args)
+	if err != nil || len(pad) > 1 {
+		return "", fmt.Errorf("padLeft/padRight take a width, optionally a padding string, and a value")
+	}
+	s, p := toText(value), " "
+	if len(pad) > 0 && pad[0] != "" {
+		p = pad[0]
+	}
+	missing := width - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return s, nil
+	}
+	padding := []rune(strings.Repeat(p, missing/utf8.RuneCountInString(p)+1))[:missing]
+	if left {
+		return string(padding) + s, nil
+	}
+	return s + string(padding), nil
+}
+
+// Split the arguments of a function taking the value last, so that it can be piped : the string options, then the value.
+func pipedValue(name string, args []any) (value any, options []string, err error) {
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("%s : missing value", name)
+	}
+	for _, a := range args[:len(args)-1] {
+		o, ok := a.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s : unexpected option %v, a string is expected", name, a)
+		}
+		options = append(options, o)
+	}
+	return args[len(args)-1], options, nil
+}
+
+// Get the value, or the default value if the value is empty.
+func tplDefault(def any, value any) any {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+// Get an element of a map, a slice or an array, or an empty string if there is none.
+// Pointers and interfaces are followed, and keys are converted to the key type of the map when possible.
+func tplGet(collection any, key any) any {
+	v := reflect.ValueOf(collection)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		k := reflect.ValueOf(key)
+		if !k.IsValid() {
+			return ""
+		}
+		if !k.Type().AssignableTo(v.Type().Key()) {
+			if !k.Type().ConvertibleTo(v.Type().Key()) || (k.Kind() == reflect.String) != (v.Type().Key().Kind() == reflect.String) {
+				return ""
+			}
+			k = k.Convert(v.Type().Key())
+		}
+		if e := v.MapIndex(k); e.IsValid() {
+			return e.Interface()
+		}
+	case reflect.Slice, reflect.Array:
+		f, err := toFloat(key)
+		if i := int(f); err == nil && float64(i) == f && i >= 0 && i < v.Len() {
+			return v.Index(i).Interface()
+		}
+	}
+	return ""
+}
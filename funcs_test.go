@@ -0,0 +1,55 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateFunctions(t *testing.T) {
+
+	data := map[string]any{
+		"Total":  1234567.891,
+		"Due":    time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC),
+		"Items":  []string{"a", "b"},
+		"Tags":   map[string]int{"vip": 1},
+		"Name":   "jean-pierre o'neil",
+		"Empty":  "",
+		"Amount": 80.01,
+	}
+	for _, tc := range []struct{ tpl, want string }{
+		{`{{join .Items ", "}}`, "a, b"},
+		{`{{formatNumber 2 .Total}} {{formatNumber 0 "fr" .Total}} {{formatNumber 2 -0.001}} {{formatNumber 1 "fr-FR" "12.5"}}`, "1,234,567.89 1\u00a0234\u00a0568 0.00 12,5"},
+		{`{{currency .Total "EUR"}} {{currency -5 "usd"}} {{currency 1500 "CHF"}} {{currency 1500.4 "JPY"}}`, "€1,234,567.89 -$5.00 CHF\u00a01,500.00 ¥1,500"},
+		{`{{currency .Total "EUR" "fr"}} {{currency 3 "CHF" "fr"}}`, "1\u00a0234\u00a0567,89\u00a0€ 3,00\u00a0CHF"},
+		{`{{percent 0.125 1}} {{percent 0.125 0 "fr"}}`, "12.5% 13\u00a0%"},
+		{`{{words 0}} / {{words 21}} / {{words 1234}} / {{words -2000015}}`, "zero / twenty-one / one thousand two hundred thirty-four / minus two million fifteen"},
+		{`{{words 71 "fr"}} / {{words 80 "fr"}} / {{words 81 "fr"}} / {{words 91 "fr"}} / {{words 200 "fr"}} / {{words 280000 "fr"}}`,
+			"soixante et onze / quatre-vingts / quatre-vingt-un / quatre-vingt-onze / deux cents / deux cent quatre-vingt mille"},
+		{`{{words 1001 "fr"}} / {{words 200000000 "fr"}} / {{words 1234567 "fr"}}`, "mille un / deux cents millions / un million deux cent trente-quatre mille cinq cent soixante-sept"},
+		{`{{amountWords .Amount "EUR"}} / {{amountWords 1 "GBP"}} / {{amountWords 0.5 "USD"}}`, "eighty euros and one cent / one pound / fifty cents"},
+		{`{{amountWords .Amount "EUR" "fr"}} / {{amountWords 1000000 "EUR" "fr"}} / {{amountWords 2000000 "USD" "fr"}} / {{amountWords 21.2 "CHF" "fr"}}`,
+			"quatre-vingts euros et un centime / un million d'euros / deux millions de dollars / vingt et un francs suisses et vingt centimes"},
+		{`{{formatDate .Due "Monday 2 January 2006"}} / {{formatDate .Due "Mon 2 Jan" "fr"}} / {{formatDate "2025-03-01" "02/01/2006"}}`, "Friday 31 January 2025 / ven. 31 janv. / 01/03/2025"},
+		{`{{formatDate (addMonths .Due 1) "2006-01-02"}} {{formatDate (addDays .Due -31) "2006-01-02"}} {{formatDate (addYears "2024-02-29" 1) "2006-01-02"}}`, "2025-03-03 2024-12-31 2025-03-01"},
+		{`{{daysBetween .Due "2025-03-01"}} {{daysBetween "2025-03-01T23:00:00Z" .Due}} {{formatDate (parseDate "31/01/2025" "02/01/2006") "January 2"}}`, "29 -29 January 31"},
+		{`{{len .Items}} {{plural (len .Items) "item" "items"}}, 1 {{plural 1 "item" "items"}}, 0 {{plural 0 "item" "items"}}, 0 {{plural 0 "article" "articles" "fr"}}`, "2 items, 1 item, 0 items, 0 article"},
+		{`{{upper .Name}} {{title .Name}} {{lower "ÉTÉ"}}`, "JEAN-PIERRE O'NEIL Jean-Pierre O'neil été"},
+		{`[{{padLeft 5 42}}] [{{padRight 5 ".-" "ab"}}] [{{padLeft 3 "0" "abcdef"}}] [{{padLeft 3 "0" 7}}]`, "[   42] [ab.-.] [abcdef] [007]"},
+		{`{{default "none" .Empty}} {{default "none" .Name}} {{default 0 .Missing}} {{default 5 0}}`, "none jean-pierre o'neil 0 5"},
+		{`{{.Name | default "n/a"}} {{.Missing | default "n/a"}} {{.Amount | formatNumber 2}} {{.Total | formatNumber 0 "fr"}} [{{42 | padLeft 5}}] [{{"ab" | padRight 5 ".-"}}]`, "jean-pierre o'neil n/a 80.01 1\u00a0234\u00a0568 [   42] [ab.-.]"},
+		{`{{get .Tags "vip"}}|{{get .Tags "other"}}|{{get .Items 1}}|{{get .Items 5}}|{{get .Missing 0}}|{{get .Items -1 | default "none"}}`, "1||b|||none"},
+	} {
+		got := NewTplReplacer(data)("", tc.tpl)
+		if strings.Join(got, "|") != tc.want {
+			t.Errorf("%s\ngot  %q\nwant %q", tc.tpl, got, tc.want)
+		}
+	}
+
+	// errors
+	for _, tpl := range []string{`{{formatNumber 2 "abc"}}`, `{{formatNumber 2}}`, `{{padLeft 5 1 2}}`, `{{currency 1 "EUR" "de"}}`, `{{words 1.5}}`, `{{amountWords 1 "XYZ"}}`, `{{formatDate "tomorrow" "2006"}}`} {
+		if got := NewTplReplacer(data)("", tpl); len(got) != 2 || !strings.HasPrefix(got[1], errorBanner) {
+			t.Errorf("%s : expected an error, got %q", tpl, got)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package mydocx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"  // register gif decoder, for image sizes
+	_ "image/jpeg" // register jpeg decoder, for image sizes
+	_ "image/png"  // register png decoder, for image sizes
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Relationship type of images.
+const imageRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+
+// Namespace of the legacy VML pictures, found in alternate content fallbacks.
+const vmlNamespace = "urn:schemas-microsoft-com:vml"
+
+// Drawing units (EMU) per point, and per pixel at 96 dpi.
+const (
+	emuPerPoint = 12700
+	emuPerPixel = 9525
+)
+
+// Content types of the supported image formats.
+var imageContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// Image returns the markup of the png, jpeg or gif image file, for inclusion in the text returned by a Replacer.
+// Width and height are in points (1/72 inch). If one of them is 0, it is computed to keep the image proportions.
+// If both are 0, the image is displayed with its own size, at 96 dpi.
+// When the Replacer result is written, the markup is replaced by an inline picture, and the image is embedded in the document.
+// Image is available in templates as {{image .LogoPath 120 40}}.
+func Image(path string, width, height float64) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return ImageBytes(data, width, height)
+}
+
+// Same as Image, but takes the content of the image as a byte array.
+func ImageBytes(data []byte, width, height float64) (string, error) {
+	cx, cy, format, err := imageExtent(data, width, height)
+	if err != nil {
+		return "", err
+	}
+	return inlineObject("image", format, base64.StdEncoding.EncodeToString(data), strconv.FormatInt(cx, 10), strconv.FormatInt(cy, 10)), nil
+}
+
+// Template version of Image : the source is either a file path or the content of the image,
+// the size is optional, as any number (see toFloat).
+func tplImage(source any, size ...any) (string, error) {
+	if len(size) > 2 {
+		return "", fmt.Errorf("image expects at most a width and a height, got %d sizes", len(size))
+	}
+	var wh [2]float64
+	for i, v := range size {
+		f, err := toFloat(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid image size : %v", err)
+		}
+		wh[i] = f
+	}
+	width, height := wh[0], wh[1]
+	switch s := source.(type) {
+	case string:
+		return Image(s, width, height)
+	case []byte:
+		return ImageBytes(s, width, height)
+	default:
+		return "", fmt.Errorf("image expects a file path or a byte array, got %T", source)
+	}
+}
+
+// Get the displayed size of an image, in EMU, and its format (png, jpeg or gif).
+// Width and height are in points, 0 for a size computed from the image proportions.
+func imageExtent(data []byte, width, height float64) (cx, cy int64, format string, err error) {
+	conf, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("unsupported image : %v", err)
+	}
+	if conf.Width <= 0 || conf.Height <= 0 {
+		return 0, 0, "", fmt.Errorf("invalid image size : %dx%d", conf.Width, conf.Height)
+	}
+	if width < 0 || height < 0 {
+		return 0, 0, "", fmt.Errorf("invalid image size : %vx%v", width, height)
+	}
+	ratio := float64(conf.Height) / float64(conf.Width)
+	switch {
+	case width == 0 && height == 0:
+		return int64(conf.Width) * emuPerPixel, int64(conf.Height) * emuPerPixel, format, nil
+	case height == 0:
+		height = width * ratio
+	case width == 0:
+		width = height / ratio
+	}
+	return int64(width * emuPerPoint), int64(height * emuPerPoint), format, nil
+}
+
+// Render an image inline object, with arguments format, base64 content, width and height (EMU), and return its xml.
+// The image is added to the package once, even if it is inserted several times.
+func (md *modifier) image(args []string, rPr *xnode) (string, error) {
+	if len(args) < 4 {
+		return "", fmt.Errorf("invalid image")
+	}
+	if _, ok := imageContentTypes[args[0]]; !ok {
+		return "", fmt.Errorf("unsupported image format : %s", args[0])
+	}
+	if md.pkg == nil {
+		return "", fmt.Errorf("no package to add the image to")
+	}
+	data, err := base64.StdEncoding.DecodeString(args[1])
+	if err != nil {
+		return "", err
+	}
+	name := md.pkg.addMedia(data, args[0])
+	id, err := md.pkg.addRelationship(md.container, imageRelType, name, false)
+	if err != nil {
+		return "", err
+	}
+	md.pkg.changes.drawing++
+	return `<w:r>` + mergeRunProperties(rPr, `<w:noProof/>`) + `<w:drawing>` + drawingInline(md.pkg.changes.drawing, id, args[2], args[3]) + `</w:drawing></w:r>`, nil
+}
+
+// Add an image to the package, in the word/media folder, and return its part name.
+// The format is the format reported by the image package (png, jpeg or gif).
+// An image is added only once : adding the same content again returns the same part name.
+func (pkg *docxPackage) addMedia(data []byte, format string) string {
+	pc := &pkg.changes
+	if name, ok := pc.media[string(data)]; ok {
+		return name
+	}
+	name := ""
+	for i := 1; name == "" || pkg.hasPart(name); i++ {
+		name = fmt.Sprintf("word/media/image%d.%s", i, strings.Replace(format, "jpeg", "jpg", 1))
+	}
+	pkg.addPart(name, imageContentTypes[format], data)
+	if pc.media == nil {
+		pc.media = make(map[string]string)
+	}
+	pc.media[string(data)] = name
+	return name
+}
+
+// Get the xml of an inline picture, with its unique drawing id, the relationship id of the image and its size in EMU.
+func drawingInline(drawingID int, relID string, cx, cy string) string {
+	name := fmt.Sprintf("Picture %d", drawingID)
+	return `<wp:inline distT="0" distB="0" distL="0" distR="0">` +
+		`<wp:extent cx="` + cx + `" cy="` + cy + `"/><wp:effectExtent l="0" t="0" r="0" b="0"/>` +
+		`<wp:docPr id="` + strconv.Itoa(drawingID) + `" name="` + name + `"/>` +
+		`<wp:cNvGraphicFramePr><a:graphicFrameLocks xmlns:a="` + fragmentNamespaces["a"] + `" noChangeAspect="1"/></wp:cNvGraphicFramePr>` +
+		`<a:graphic xmlns:a="` + fragmentNamespaces["a"] + `"><a:graphicData uri="` + fragmentNamespaces["pic"] + `">` +
+		`<pic:pic xmlns:pic="` + fragmentNamespaces["pic"] + `"><pic:nvPicPr><pic:cNvPr id="0" name="` + name + `"/><pic:cNvPicPr/></pic:nvPicPr>` +
+		`<pic:blipFill><a:blip r:embed="` + relID + `"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>` +
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="` + cx + `" cy="` + cy + `"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>` +
+		`</pic:pic></a:graphicData></a:graphic></wp:inline>`
+}
+
+// Record the drawing ids used in the tree, so that new drawings get unique ids.
+func (pc *packageChanges) useDrawingIDs(root *xnode) {
+	root.walk(func(n *xnode) bool {
+		if n.isNS(fragmentNamespaces["wp"], "docPr") {
+			v, _ := n.attrValue("", "id")
+			if id, err := strconv.Atoi(v); err == nil && id > pc.drawing {
+				pc.drawing = id
+			}
+		}
+		return true
+	})
+}
+
+// Replace the images of the docx file whose name or description (alt text) matches a key of the map, by the provided png, jpeg or gif content.
+// The size, position and formatting of the pictures are kept, only the image itself is changed.
+// Designers can thus place and tag a sample picture in a template, to be replaced by the actual image.
+// Images with no matching key are left unchanged.
+// If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
+// Images can also be replaced while modifying the text, see WithImages.
+func ReplaceImages(sourceFilePath string, images map[string][]byte, targetFilePath string, opts ...Option) error {
+	return modifyFile(sourceFilePath, targetFilePath, "replace images", func(in []byte) ([]byte, error) {
+		return ReplaceImagesBytes(in, images, opts...)
+	})
+}
+
+// Same as ReplaceImages, but takes a byte array as input and returns the modified docx as a byte array.
+func ReplaceImagesBytes(sourceBytes []byte, images map[string][]byte, opts ...Option) ([]byte, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input bytes: %v", err)
+	}
+	return pkg.rewrite(conf.parts, func(fname string, root *xnode) error {
+		return pkg.replaceImages(fname, root, images)
+	})
+}
+
+// Replace the images of the container tree whose name or description matches a key of the map.
+// The pictures refer to a new image part, through a new relationship. The previous image part is left in the package.
+func (pkg *docxPackage) replaceImages(container string, root *xnode, images map[string][]byte) error {
+	if len(images) == 0 {
+		return nil
+	}
+	var err error
+	root.walk(func(n *xnode) bool {
+		if err != nil || !n.isNS(fragmentNamespaces["wp"], "docPr") || n.parent == nil {
+			return err == nil
+		}
+		data, ok := imageData(n, images)
+		if !ok {
+			return true
+		}
+		var format string
+		if _, _, format, err = imageExtent(data, 0, 0); err != nil {
+			return false
+		}
+		var id string
+		if id, err = pkg.addRelationship(container, imageRelType, pkg.addMedia(data, format), false); err != nil {
+			return false
+		}
+		n.parent.walk(func(b *xnode) bool {
+			if b.isNS(fragmentNamespaces["a"], "blip") {
+				if old, ok := b.attrValue(RELATIONSHIPS_NAMESPACE, "embed"); ok {
+					b.setAttr("r:embed", id)
+					replaceVMLImage(n, old, id)
+				}
+			}
+			return true
+		})
+		return true
+	})
+	return err
+}
+
+// Get the image matching the description (alt text), or else the name, of a drawing.
+func imageData(docPr *xnode, images map[string][]byte) ([]byte, bool) {
+	for _, attr := range []string{"descr", "name"} {
+		if v, ok := docPr.attrValue("", attr); ok && v != "" {
+			if data, found := images[v]; found {
+				return data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Update the VML copy of a picture, in the alternate content fallback around the drawing, if any,
+// so that it refers to the same new image.
+func replaceVMLImage(n *xnode, old, id string) {
+	for ; n != nil; n = n.parent {
+		if n.isNS(mcNamespace, "AlternateContent") {
+			n.walk(func(v *xnode) bool {
+				if v.isNS(vmlNamespace, "imagedata") {
+					if rid, ok := v.attrValue(RELATIONSHIPS_NAMESPACE, "id"); ok && rid == old {
+						v.setAttr("r:id", id)
+					}
+				}
+				return true
+			})
+			return
+		}
+	}
+}
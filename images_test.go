@@ -0,0 +1,168 @@
+package mydocx
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// Build a png image of the provided size, in pixels.
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImages(t *testing.T) {
+
+	body := `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Logo: {{image .Logo 120}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{image .Logo}} and {{image .Logo 0 30}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="7" name="Existing"/></wp:inline></w:drawing></w:r></w:p>`
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:               testContentTypes,
+		"word/document.xml":            testDocument(body),
+		"word/_rels/document.xml.rels": testDocumentRels,
+	})
+
+	out, err := ModifyTextBytes(docx, NewTplReplacer(map[string]any{"Logo": testPNG(t, 40, 20)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pp["word/document.xml"], "|"); got != "Logo: | and |" {
+		t.Errorf("unexpected text : %q", got)
+	}
+
+	pkg, err := openPackage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkg.hasPart("word/media/image1.png") || pkg.hasPart("word/media/image2.png") {
+		t.Errorf("the image should be embedded once")
+	}
+	if ct := pkg.contentType("word/media/image1.png"); ct != "image/png" {
+		t.Errorf("unexpected content type : %q", ct)
+	}
+	rels, err := pkg.rels("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 4 || rels["rId2"].Target != "word/media/image1.png" || rels["rId2"].Type != imageRelType {
+		t.Errorf("unexpected relationships : %+v", rels)
+	}
+	if content, err := pkg.read("word/_rels/document.xml.rels"); err != nil || !strings.Contains(string(content), `Target="media/image1.png"`) {
+		t.Errorf("image targets should be relative to their source : %s", content)
+	}
+
+	content, err := pkg.read("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(content)
+	for _, want := range []string{
+		`<w:rPr><w:b/><w:noProof/></w:rPr><w:drawing>`,                    // formatting of the placeholder run
+		`<wp:extent cx="1524000" cy="762000"/>`,                           // 120pt wide, proportional height
+		`<wp:extent cx="381000" cy="190500"/>`,                            // 40x20 pixels at 96 dpi
+		`<wp:extent cx="762000" cy="381000"/>`,                            // 30pt high, proportional width
+		`<wp:docPr id="8" name="Picture 8"/>`, `<a:blip r:embed="rId2"/>`, // unique drawing ids
+		`<wp:docPr id="10" name="Picture 10"/>`, `<a:blip r:embed="rId4"/>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+
+	// invalid images are reported by the template
+	out, err = ModifyTextBytes(docx, NewTplReplacer(map[string]any{"Logo": []byte("not an image")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err = ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) < 2 || !strings.Contains(got[1], "unsupported image") {
+		t.Errorf("unexpected text : %q", got)
+	}
+
+	// sizes of any number type
+	logo := testPNG(t, 40, 20)
+	want, err := ImageBytes(logo, 80, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replace := NewTplReplacer(map[string]any{"Logo": logo, "Width": 80, "Height": int32(30), "Text": "30"})
+	for _, tpl := range []string{"{{image .Logo .Width .Height}}", "{{image .Logo 80.0 .Text}}"} {
+		if got := replace("", tpl); len(got) != 1 || got[0] != want {
+			t.Errorf("%s : unexpected result %q", tpl, got)
+		}
+	}
+	if got := replace("", "{{image .Logo .Logo}}"); len(got) != 2 || !strings.Contains(got[1], "invalid image size") {
+		t.Errorf("unexpected result %q", got)
+	}
+}
+
+// picture tagged with its alt text, and its VML copy in an alternate content fallback
+const testPicture = `<w:p><w:r><mc:AlternateContent><mc:Choice Requires="wps"><w:drawing><wp:anchor><wp:extent cx="100" cy="50"/><wp:docPr id="1" name="Picture 1" descr="photo"/>` +
+	`<a:graphic><a:graphicData><pic:pic><pic:blipFill><a:blip r:embed="rId7"/></pic:blipFill></pic:pic></a:graphicData></a:graphic></wp:anchor></w:drawing></mc:Choice>` +
+	`<mc:Fallback><w:pict><v:shape><v:imagedata r:id="rId7"/></v:shape></w:pict></mc:Fallback></mc:AlternateContent></w:r></w:p>` +
+	`<w:p><w:r><w:drawing><wp:inline><wp:extent cx="200" cy="80"/><wp:docPr id="2" name="signature"/><a:graphic><a:graphicData><pic:pic><pic:blipFill><a:blip r:embed="rId7"/></pic:blipFill></pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+	`<w:p><w:r><w:drawing><wp:inline><wp:docPr id="3" name="other"/><a:graphic><a:graphicData><pic:pic><pic:blipFill><a:blip r:embed="rId7"/></pic:blipFill></pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>` +
+	`<w:p><w:r><w:t>Hello {{.Name}}</w:t></w:r></w:p>`
+
+func TestReplaceImages(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:               testContentTypes,
+		"word/document.xml":            testDocument(testPicture),
+		"word/_rels/document.xml.rels": testDocumentRels,
+	})
+	photo, signature := testPNG(t, 10, 10), testPNG(t, 20, 10)
+
+	out, err := ModifyTextBytes(docx, NewTplReplacer(map[string]string{"Name": "John"}),
+		WithImages(map[string][]byte{"photo": photo, "signature": signature, "missing": photo}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := openPackage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := pkg.read("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(content)
+	for _, want := range []string{
+		`<wp:extent cx="100" cy="50"/><wp:docPr id="1" name="Picture 1" descr="photo"/>`, // size kept
+		`<a:blip r:embed="rId2"/>`, `<v:imagedata r:id="rId2"/>`, // VML copy replaced too
+		`<a:blip r:embed="rId3"/>`, `<a:blip r:embed="rId7"/>`, // other images unchanged
+		`Hello John`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %s in\n%s", want, doc)
+		}
+	}
+	rels, err := pkg.rels("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rels["rId2"].Target != "word/media/image1.png" || rels["rId3"].Target != "word/media/image2.png" {
+		t.Errorf("unexpected relationships : %+v", rels)
+	}
+	if data, err := pkg.read("word/media/image2.png"); err != nil || !bytes.Equal(data, signature) {
+		t.Errorf("unexpected image content : %v", err)
+	}
+
+	if _, err = ReplaceImagesBytes(docx, map[string][]byte{"photo": []byte("not an image")}); err == nil {
+		t.Errorf("invalid images should be reported")
+	}
+}
@@ -0,0 +1,204 @@
+package mydocx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Markers of the inline objects (hyperlinks, images, ...) in the text returned by a Replacer.
+// An inline object is written as : start kind sep arg1 sep arg2 ... end
+// The modifier renders inline objects as runs, hyperlinks or drawings, instead of plain text.
+// Each marker is a private use character followed by a random key, drawn when the program starts :
+// the text of the document and the data of the templates cannot forge inline objects,
+// and their own private use characters (such as icon fonts) are kept as plain text.
+var (
+	inlineKey   = randomKey()
+	inlineStart = "\uE000" + inlineKey
+	inlineSep   = "\uE001" + inlineKey
+	inlineEnd   = "\uE002" + inlineKey
+)
+
+// Get a random key, in hexadecimal.
+func randomKey() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to draw a random key : " + err.Error()) // should never happen
+	}
+	return hex.EncodeToString(b)
+}
+
+// Encode an inline object, with its kind and its arguments.
+// Marker characters are removed from the arguments, new lines are replaced by spaces.
+func inlineObject(kind string, args ...string) string {
+	var sb strings.Builder
+	sb.WriteString(inlineStart + kind)
+	for _, a := range args {
+		sb.WriteString(inlineSep + strings.ReplaceAll(stripInline(a), "\n", " "))
+	}
+	sb.WriteString(inlineEnd)
+	return sb.String()
+}
+
+// Remove the inline marker characters from a text.
+func stripInline(text string) string {
+	return strings.NewReplacer(inlineStart, "", inlineSep, "", inlineEnd, "").Replace(text)
+}
+
+// inlineSegment is a part of a replaced text : either plain text, or an inline object.
+type inlineSegment struct {
+	kind string   // kind of the inline object, empty for plain text
+	args []string // arguments of the inline object
+	text string   // plain text
+}
+
+// Split a text into plain text and inline objects. Empty plain text segments are omitted.
+// Unterminated inline objects are kept as plain text, without their markers.
+func parseInline(text string) (res []inlineSegment) {
+	for text != "" {
+		i := strings.Index(text, inlineStart)
+		if i < 0 {
+			return append(res, inlineSegment{text: text})
+		}
+		if i > 0 {
+			res = append(res, inlineSegment{text: text[:i]})
+		}
+		text = text[i+len(inlineStart):]
+		j := strings.Index(text, inlineEnd)
+		if j < 0 {
+			return append(res, inlineSegment{text: stripInline(text)})
+		}
+		parts := strings.Split(text[:j], inlineSep)
+		res = append(res, inlineSegment{kind: parts[0], args: parts[1:]})
+		text = text[j+len(inlineEnd):]
+	}
+	return res
+}
+
+// Check if a text contains inline objects.
+func hasInline(text string) bool {
+	return strings.Contains(text, inlineStart)
+}
+
+// Save the text in a text element, rendering its inline objects as new runs, inserted just after the run of the text element.
+// Plain text following an inline object is saved in a new run, with the same properties as the original run.
+func (md *modifier) setRichText(t *xnode, text string) {
+	run := t.ancestor("r")
+	if !hasInline(text) || run == nil {
+		t.setText(stripInline(text))
+		return
+	}
+	segments := parseInline(text)
+	first := ""
+	if len(segments) > 0 && segments[0].kind == "" {
+		first, segments = segments[0].text, segments[1:]
+	}
+	t.setText(first)
+	if strings.TrimSpace(first) != first {
+		t.setAttr("xml:space", "preserve")
+	}
+
+	anchor := run // new runs are inserted after the anchor
+	if run.parent != nil && run.parent.is("hyperlink") {
+		anchor = run.parent
+	}
+	for _, seg := range segments {
+		nodes := md.renderInline(seg, run.child("rPr"))
+		anchor.insertAfter(nodes...)
+		anchor = nodes[len(nodes)-1]
+	}
+}
+
+// Render an inline object, or plain text, as a list of nodes to insert in a paragraph.
+// The run properties (that may be nil) are the properties of the run where the object is inserted.
+func (md *modifier) renderInline(seg inlineSegment, rPr *xnode) []*xnode {
+	fragment := ""
+	var err error
+	switch seg.kind {
+	case "":
+		fragment = textRun(mergeRunProperties(rPr), seg.text)
+	case "link":
+		fragment, err = md.hyperlink(seg.args, rPr)
+	case "image":
+		fragment, err = md.image(seg.args, rPr)
+	case "break":
+		fragment = `<w:r>` + mergeRunProperties(rPr) + `<w:br w:type="page"/></w:r>`
+	case "format":
+		if len(seg.args) == 2 {
+			fragment = textRun(mergeRunProperties(rPr, formatRunProperties(seg.args[0])...), seg.args[1])
+		} else {
+			fragment = textRun(mergeRunProperties(rPr), strings.Join(seg.args, " "))
+		}
+	default:
+		fragment = textRun(mergeRunProperties(rPr), strings.Join(seg.args, " "))
+	}
+	if err != nil {
+		md.debug("failed to render inline object", seg.kind, err)
+		fragment = textRun(mergeRunProperties(rPr), strings.Join(seg.args, " "))
+	}
+	nodes, err := parseFragment(fragment)
+	if err != nil {
+		panic("invalid inline fragment : " + err.Error()) // should never happen
+	}
+	return nodes
+}
+
+// Get the xml of a run with the provided run properties and text.
+func textRun(rPr string, text string) string {
+	return `<w:r>` + rPr + `<w:t xml:space="preserve">` + string(xmlEscape([]byte(text))) + `</w:t></w:r>`
+}
+
+// Order of the elements of the run properties, as required by the schema.
+var runPropertiesOrder = []string{"rStyle", "rFonts", "b", "bCs", "i", "iCs", "caps", "smallCaps", "strike", "dstrike",
+	"outline", "shadow", "emboss", "imprint", "noProof", "snapToGrid", "vanish", "webHidden", "color", "spacing", "w", "kern",
+	"position", "sz", "szCs", "highlight", "u", "effect", "bdr", "shd", "fitText", "vertAlign", "rtl", "cs", "em", "lang",
+	"eastAsianLayout", "specVanish", "oMath"}
+
+// Get the xml of run properties, made of the base properties (that may be nil) overridden by the provided properties (eg : <w:b/>).
+// Properties are sorted in schema order. Returns an empty string if there are no properties.
+func mergeRunProperties(base *xnode, props ...string) string {
+	added, err := parseFragment(strings.Join(props, ""))
+	if err != nil {
+		panic("invalid run properties : " + err.Error()) // should never happen
+	}
+	var elements []*xnode
+	if base != nil {
+		for _, c := range base.children {
+			if !c.isElement() || c.is("rPrChange") {
+				continue
+			}
+			overridden := false
+			for _, a := range added {
+				overridden = overridden || (a.isElement() && a.name == c.name)
+			}
+			if !overridden {
+				elements = append(elements, c)
+			}
+		}
+	}
+	for _, a := range added {
+		if a.isElement() {
+			elements = append(elements, a)
+		}
+	}
+	if len(elements) == 0 {
+		return ""
+	}
+	rank := func(n *xnode) int {
+		for i, local := range runPropertiesOrder {
+			if n.is(local) {
+				return i
+			}
+		}
+		return len(runPropertiesOrder) // extensions come last
+	}
+	sort.SliceStable(elements, func(i, j int) bool { return rank(elements[i]) < rank(elements[j]) })
+	var sb strings.Builder
+	sb.WriteString("<w:rPr>")
+	for _, e := range elements {
+		sb.Write(e.bytes())
+	}
+	sb.WriteString("</w:rPr>")
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package mydocx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relationship type of hyperlinks to external targets.
+const hyperlinkRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+// Hyperlink describes a hyperlink of a document.
+type Hyperlink struct {
+	// Name of the container where the hyperlink was found (eg : word/document.xml)
+	Container string
+	// Displayed text of the hyperlink
+	Text string
+	// Target of the hyperlink (eg : https://example.com), resolved from the relationships of the container.
+	// Empty for links to a location within the document.
+	URL string
+	// Bookmark targeted within the document, or location within the target
+	Anchor string
+}
+
+// Link returns the markup of a hyperlink to url, displaying text, for inclusion in the text returned by a Replacer.
+// Urls starting with # link to a bookmark of the document (eg : #clause4).
+// When the Replacer result is written, the markup is replaced by an actual hyperlink, formatted as the surrounding text
+// with the Hyperlink character style, and a new relationship to the url is added to the container.
+// Link is available in templates as {{link .URL "text"}}.
+func Link(url string, text string) string {
+	return inlineObject("link", url, text)
+}
+
+// Render a hyperlink inline object, with arguments url and text, and return its xml.
+func (md *modifier) hyperlink(args []string, rPr *xnode) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		return "", fmt.Errorf("missing hyperlink target")
+	}
+	url, text := args[0], args[0]
+	if len(args) > 1 && args[1] != "" {
+		text = args[1]
+	}
+	props := mergeRunProperties(rPr, `<w:rStyle w:val="Hyperlink"/>`, `<w:color w:val="0563C1"/>`, `<w:u w:val="single"/>`)
+	run := textRun(props, text)
+	if anchor, ok := strings.CutPrefix(url, "#"); ok {
+		return `<w:hyperlink w:anchor="` + string(xmlEscape([]byte(anchor))) + `" w:history="1">` + run + `</w:hyperlink>`, nil
+	}
+	if md.pkg == nil {
+		return "", fmt.Errorf("no package to add the hyperlink relationship to")
+	}
+	id, err := md.pkg.addRelationship(md.container, hyperlinkRelType, url, true)
+	if err != nil {
+		return "", err
+	}
+	return `<w:hyperlink r:id="` + id + `" w:history="1">` + run + `</w:hyperlink>`, nil
+}
+
+// Extract the hyperlinks of the docx file, with their text and their resolved target,
+// in reading order of the containers, then document order.
+// Both hyperlink elements and HYPERLINK fields are reported. Text is extracted as if all changes were accepted.
+func ExtractHyperlinks(sourceFilePath string, opts ...Option) ([]Hyperlink, error) {
+	return fromFile(sourceFilePath, ExtractHyperlinksBytes, opts)
+}
+
+// Same as ExtractHyperlinks, but takes a byte array as input.
+func ExtractHyperlinksBytes(sourceBytes []byte, opts ...Option) ([]Hyperlink, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	containers, err := pkg.containers(conf.parts)
+	if err != nil {
+		return nil, err
+	}
+	var res []Hyperlink
+	for _, c := range containers {
+		content, err := pkg.read(c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", c.Name, err)
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", c.Name, err)
+		}
+		rels, err := pkg.rels(c.Name)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[*xnode]*field) // HYPERLINK fields, by their first node
+		for _, f := range findFields(root) {
+			if first, _ := f.bounds(); parseInstruction(f.instruction()).kind == "HYPERLINK" {
+				fields[first] = f
+			}
+		}
+		root.walk(func(n *xnode) bool {
+			switch {
+			case n.isFallback():
+				return false
+			case n.is("hyperlink"):
+				h := Hyperlink{Container: c.Name, Text: paragraphText(n, acceptedView)}
+				if h.Text == "" {
+					return false // invisible, such as a link whose text was moved by a Replacer
+				}
+				h.Anchor, _ = n.attrValue(NAMESPACE, "anchor")
+				if id, ok := n.attrValue(RELATIONSHIPS_NAMESPACE, "id"); ok {
+					h.URL = rels[id].Target
+				}
+				res = append(res, h)
+			case fields[n] != nil:
+				f := fields[n]
+				fi := parseInstruction(f.instruction())
+				h := Hyperlink{Container: c.Name, Text: f.result()}
+				if len(fi.args) > 0 {
+					h.URL = fi.args[0]
+				}
+				h.Anchor, _ = fi.switchArg("l")
+				res = append(res, h)
+			}
+			return true
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,116 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+// hyperlinks : external link with its relationship, link to a bookmark, and HYPERLINK field
+var testLinks = `<w:p><w:r><w:t xml:space="preserve">See </w:t></w:r><w:hyperlink r:id="rId7" w:history="1"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t>our site</w:t></w:r></w:hyperlink></w:p>` +
+	`<w:p><w:hyperlink w:anchor="terms"><w:r><w:t>Terms</w:t></w:r></w:hyperlink></w:p>` +
+	`<w:p>` + testField(` HYPERLINK "https://example.org/faq" \l "q1" `, "FAQ") + `</w:p>` +
+	`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Visit {{link .URL "the shop"}} today</w:t></w:r></w:p>`
+
+const testDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com" TargetMode="External"/></Relationships>`
+
+func TestHyperlinks(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:               testContentTypes,
+		"word/document.xml":            testDocument(testLinks),
+		"word/_rels/document.xml.rels": testDocumentRels,
+	})
+
+	links, err := ExtractHyperlinksBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Hyperlink{
+		{"word/document.xml", "our site", "https://example.com", ""},
+		{"word/document.xml", "Terms", "", "terms"},
+		{"word/document.xml", "FAQ", "https://example.org/faq", "q1"},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("got %+v, want %+v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("link %d : got %+v, want %+v", i, links[i], want[i])
+		}
+	}
+
+	out, err := ModifyTextBytes(docx, NewTplReplacer(map[string]string{"URL": "https://shop.example.com/?a=1&b=2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"][3]; got != "Visit the shop today" {
+		t.Errorf("unexpected text : %q", got)
+	}
+	links, err = ExtractHyperlinksBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := links[len(links)-1]; last.URL != "https://shop.example.com/?a=1&b=2" || last.Text != "the shop" {
+		t.Fatalf("unexpected links : %+v", links)
+	}
+
+	// the new link keeps the formatting of its run, with the hyperlink style, and has its own relationship
+	pkg, err := openPackage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rels, err := pkg.rels("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 2 || rels["rId7"].Target != "https://example.com" {
+		t.Errorf("unexpected relationships : %+v", rels)
+	}
+	content, err := pkg.read("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `<w:rPr><w:rStyle w:val="Hyperlink"/><w:b/><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr><w:t xml:space="preserve">the shop</w:t>`) {
+		t.Errorf("unexpected hyperlink formatting :\n%s", content)
+	}
+
+	// links to bookmarks do not need a relationship
+	out, err = ModifyTextBytes(docx, func(_, s string) []string {
+		return []string{strings.ReplaceAll(s, "Terms", Link("#terms", "the terms"))}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err = ExtractHyperlinksBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) == 0 || links[0].Text != "the terms" || links[0].Anchor != "terms" {
+		t.Fatalf("unexpected links : %+v", links)
+	}
+
+	// private use characters of the document and of the data cannot forge inline objects, and are kept
+	icons := "\uE000link\uE001https://evil.example.com\uE001click\uE002"
+	docx = makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("Icons "+icons) + testPara("{{.Text}}")),
+	})
+	out, err = ModifyTextBytes(docx, NewTplReplacer(map[string]string{"Text": icons}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp, err = ExtractTextBytes(out); err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) != 2 || got[0] != "Icons "+icons || got[1] != icons {
+		t.Errorf("unexpected text : %q", got)
+	}
+	if links, err = ExtractHyperlinksBytes(out); err != nil || len(links) != 0 {
+		t.Errorf("unexpected links : %+v, %v", links, err)
+	}
+}
@@ -0,0 +1,51 @@
+package mydocx
+
+import (
+	"fmt"
+)
+
+// Fill the MERGEFIELD fields of a Word mail merge template from a data record, as Word does when merging to a new document :
+// each field is replaced by its value, as plain text, and the field itself is removed.
+// Data can be a map with string keys, or a struct (fields are matched by their `docx:"name"` struct tag, or by their name, ignoring case).
+// Field switches are applied : date (\@ "dd/MM/yyyy"), numeric (\# "#,##0.00"), format (\* Upper, \* FirstCap, \* Caps, \* roman ...),
+// and the text to insert before (\b) or after (\f) a non empty value.
+// Merge fields with no value in the data record are left unchanged.
+// Other fields (IF, NEXT, ...) are left unchanged, merge fields nested in their instruction are replaced by their value.
+// If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
+func MergeFields(sourceFilePath string, data any, targetFilePath string, opts ...Option) error {
+	return modifyFile(sourceFilePath, targetFilePath, "merge fields", func(in []byte) ([]byte, error) {
+		return MergeFieldsBytes(in, data, opts...)
+	})
+}
+
+// Same as MergeFields, but takes a byte array as input and returns the merged docx as a byte array.
+func MergeFieldsBytes(sourceBytes []byte, data any, opts ...Option) ([]byte, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input bytes: %v", err)
+	}
+	return pkg.rewrite(conf.parts, func(_ string, root *xnode) error {
+		all := findFields(root)
+		for _, f := range all {
+			if !f.closed() {
+				continue
+			}
+			fi := parseInstruction(f.instruction())
+			if fi.kind != "MERGEFIELD" || len(fi.args) == 0 {
+				continue
+			}
+			value, ok := lookupValue(data, fi.args[0])
+			if !ok {
+				continue
+			}
+			text := formatFieldValue(value, fi)
+			if VERBOSE {
+				fmt.Printf("Merging field %q : %q\n", fi.args[0], text)
+			}
+			f.setResult(text)
+			f.unwrap(all)
+		}
+		return nil
+	})
+}
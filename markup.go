@@ -0,0 +1,177 @@
+package mydocx
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Run properties of the inline formats : bold, italic and underline.
+var formatProperties = map[rune][]string{
+	'b': {"<w:b/>", "<w:bCs/>"},
+	'i': {"<w:i/>", "<w:iCs/>"},
+	'u': {`<w:u w:val="single"/>`},
+}
+
+// Markup of the inline formats, longest first, since they are matched greedily.
+var markupFormats = []struct {
+	marker string
+	format rune
+}{{"**", 'b'}, {"__", 'u'}, {"*", 'i'}}
+
+// Apply an inline format (b, i or u) to a text, as inline objects rendered as runs with the format added to the run properties.
+// Text already formatted keeps its formats, other inline objects (hyperlinks, images) are left unchanged.
+// Lines are formatted separately, so that they still produce separate paragraphs.
+func addFormat(text string, format rune) string {
+	var sb strings.Builder
+	for _, seg := range parseInline(text) {
+		switch seg.kind {
+		case "":
+			for i, line := range strings.Split(seg.text, "\n") {
+				if i > 0 {
+					sb.WriteString("\n")
+				}
+				if line != "" {
+					sb.WriteString(inlineObject("format", string(format), line))
+				}
+			}
+		case "format":
+			if len(seg.args) == 2 {
+				sb.WriteString(inlineObject("format", mergeFormats(seg.args[0], string(format)), seg.args[1]))
+			}
+		default:
+			sb.WriteString(inlineObject(seg.kind, seg.args...))
+		}
+	}
+	return sb.String()
+}
+
+// Merge two lists of formats, without duplicates.
+func mergeFormats(a, b string) string {
+	for _, f := range b {
+		if !strings.ContainsRune(a, f) {
+			a += string(f)
+		}
+	}
+	return a
+}
+
+// Get the run properties of a list of formats.
+func formatRunProperties(formats string) (props []string) {
+	for _, f := range formats {
+		props = append(props, formatProperties[f]...)
+	}
+	return props
+}
+
+// markupToken is a part of a text with markup : plain text, or a marker opening or closing a format.
+type markupToken struct {
+	text   string
+	format rune // format of a marker, 0 for plain text
+}
+
+// Convert the inline markup of a replaced paragraph (**bold**, *italic*, __underline__) into formatted inline objects (see addFormat).
+// As in markdown (CommonMark), an opening marker is followed by a non space character, and a closing marker follows a non space character.
+// Underscores only open and close at word boundaries : my__var__name is plain text.
+// Markers that are not closed in the paragraph, and markers preceded by a backslash, are plain text.
+// Inline objects (hyperlinks, images, formatted text) are not scanned for markup, formats still apply to formatted text.
+func parseMarkup(text string) string {
+	if !strings.ContainsAny(text, "*_") {
+		return text
+	}
+	segments := parseInline(text)
+
+	// find the markers, and the format they open or close
+	var tokens []markupToken
+	objects := make(map[int]inlineSegment) // inline objects, by token index
+	open := make(map[rune]int)             // index of the opening token of the open formats
+	for k, seg := range segments {
+		if seg.kind != "" {
+			objects[len(tokens)] = seg
+			tokens = append(tokens, markupToken{})
+			continue
+		}
+		s := seg.text
+		var plain strings.Builder
+		for i := 0; i < len(s); {
+			if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '*' || s[i+1] == '_') {
+				plain.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+			found := false
+			for _, m := range markupFormats {
+				if !strings.HasPrefix(s[i:], m.marker) {
+					continue
+				}
+				// the text before and after the marker, an inline object counting as text
+				before, after := 'x', 'x'
+				if i > 0 || k == 0 {
+					before, _ = utf8.DecodeLastRuneInString(s[:i])
+				}
+				if i+len(m.marker) < len(s) || k == len(segments)-1 {
+					after, _ = utf8.DecodeRuneInString(s[i+len(m.marker):])
+				}
+				_, isOpen := open[m.format]
+				canOpen, canClose := flanking(m.marker, before, after)
+				if (isOpen && canClose) || (!isOpen && canOpen) {
+					tokens = append(tokens, markupToken{text: plain.String()})
+					plain.Reset()
+					if isOpen {
+						delete(open, m.format)
+					} else {
+						open[m.format] = len(tokens)
+					}
+					tokens = append(tokens, markupToken{text: m.marker, format: m.format})
+					found = true
+				}
+				break
+			}
+			if found {
+				i += len(tokens[len(tokens)-1].text)
+				continue
+			}
+			plain.WriteByte(s[i])
+			i++
+		}
+		tokens = append(tokens, markupToken{text: plain.String()})
+	}
+	for _, i := range open {
+		tokens[i].format = 0 // not closed : plain text
+	}
+
+	// format the text between the markers
+	var sb strings.Builder
+	formats := ""
+	for i, t := range tokens {
+		switch {
+		case t.format != 0 && strings.ContainsRune(formats, t.format):
+			formats = strings.ReplaceAll(formats, string(t.format), "")
+		case t.format != 0:
+			formats += string(t.format)
+		default:
+			s := t.text
+			if seg, ok := objects[i]; ok {
+				s = inlineObject(seg.kind, seg.args...)
+			}
+			for _, f := range formats {
+				s = addFormat(s, f)
+			}
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+// Check if a marker can open or close a format, given the characters before and after it (utf8.RuneError at the ends of the text),
+// following the flanking rules of CommonMark : underscores within a word neither open nor close a format.
+func flanking(marker string, before, after rune) (canOpen, canClose bool) {
+	space := func(r rune) bool { return r == utf8.RuneError || unicode.IsSpace(r) }
+	punct := func(r rune) bool { return r != utf8.RuneError && (unicode.IsPunct(r) || unicode.IsSymbol(r)) }
+	left := !space(after) && (!punct(after) || space(before) || punct(before))
+	right := !space(before) && (!punct(before) || space(after) || punct(after))
+	if marker[0] == '_' {
+		return left && (!right || punct(before)), right && (!left || punct(after))
+	}
+	return left, right
+}
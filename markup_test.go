@@ -0,0 +1,87 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+// Show the formatted inline objects of a text as [formats:text].
+func showFormats(text string) string {
+	var sb strings.Builder
+	for _, seg := range parseInline(text) {
+		switch seg.kind {
+		case "":
+			sb.WriteString(seg.text)
+		default:
+			sb.WriteString("[" + strings.Join(seg.args, ":") + "]")
+		}
+	}
+	return sb.String()
+}
+
+func TestMarkup(t *testing.T) {
+
+	for _, tc := range []struct{ text, want string }{
+		{"Dear **Ann**, you owe *100 €*.", "Dear [b:Ann], you owe [i:100 €]."},
+		{"__Total__ : ***all*** and **bold *both***", "[u:Total] : [bi:all] and [b:bold ][bi:both]"},
+		{"5 * 3 * 2, a ** b, snake_case, __init", "5 * 3 * 2, a ** b, snake_case, __init"},
+		{"my__var__name, snake__case__ and __init__", "my__var__name, snake__case__ and [u:init]"},
+		{"**(note)**, 2*3*4", "[b:(note)], 2[i:3]4"},
+		{`not \*italic\* nor \_\_underlined__`, "not *italic* nor __underlined__"},
+		{"**unclosed and *italic*", "**unclosed and [i:italic]"},
+		{"**See " + Link("https://example.com", "the site") + " now**", "[b:See ][https://example.com:the site][b: now]"},
+		{"*" + addFormat("Ann", 'b') + "*", "[bi:Ann]"},
+	} {
+		if got := showFormats(parseMarkup(tc.text)); got != tc.want {
+			t.Errorf("parseMarkup(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+
+	// template functions, and runs inheriting the properties of the paragraph run
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(`<w:p><w:r><w:rPr><w:rFonts w:ascii="Arial"/><w:i/><w:sz w:val="20"/></w:rPr><w:t>Dear {{bold .Name}}, **{{.Amount}}** is due {{underline (bold "today")}}</w:t></w:r></w:p>`),
+	})
+	data := map[string]string{"Name": "Ann", "Amount": "100 €"}
+	for _, markup := range []bool{false, true} {
+		var opts []Option
+		if markup {
+			opts = append(opts, WithMarkup())
+		}
+		out, err := ModifyTextBytes(docx, NewTplReplacer(data), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pp, err := ExtractTextBytes(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "Dear Ann, **100 €** is due today"
+		if markup {
+			want = "Dear Ann, 100 € is due today"
+		}
+		if got := pp["word/document.xml"][0]; got != want {
+			t.Errorf("unexpected text : %q", got)
+		}
+		pkg, err := openPackage(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := pkg.read("word/document.xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs := []string{
+			`<w:r><w:rPr><w:rFonts w:ascii="Arial"/><w:b/><w:bCs/><w:i/><w:sz w:val="20"/></w:rPr><w:t xml:space="preserve">Ann</w:t></w:r>`,
+			`<w:r><w:rPr><w:rFonts w:ascii="Arial"/><w:b/><w:bCs/><w:i/><w:sz w:val="20"/><w:u w:val="single"/></w:rPr><w:t xml:space="preserve">today</w:t></w:r>`,
+		}
+		if markup {
+			runs = append(runs, `<w:r><w:rPr><w:rFonts w:ascii="Arial"/><w:b/><w:bCs/><w:i/><w:sz w:val="20"/></w:rPr><w:t xml:space="preserve">100 €</w:t></w:r>`)
+		}
+		for _, r := range runs {
+			if !strings.Contains(string(content), r) {
+				t.Errorf("missing run %s in :\n%s", r, content)
+			}
+		}
+	}
+}
@@ -1,16 +1,13 @@
 package mydocx
 
 import (
-	"archive/zip"
-	"bytes"
-	"encoding/xml"
 	"fmt"
-	"io"
-	"os"
+	"slices"
+	"strings"
 )
 
 // A Replacer replaces a string with a list of modified string. It is provided the container name where replacement will occur ("word/document.xm", "word/footer1.xml", ...).
-// Only documents, headers and footers will be submitted.
+// Only the selected containers will be submitted (by default : documents, headers and footers, see WithParts).
 // If the returned slice is empty, the paragraph is removed (unless the REMOVE_EMPTY_PRAGRAPHS flag was unset)
 // If the returned slice contains more than 1 element, new paragraphs are added, duplicated from the original paragraph.
 // The strings will be xml-escaped later, the Replacer should not escape its results.
@@ -20,38 +17,53 @@ type Replacer func(container string, original string) (replaced []string)
 // Before calling Replacer, the whole paragraph is collected as a single text, even if split on multiple runs.
 // Replacer is called paragraph by paragraph. It is never called on empty paragraphs.
 // If the Replacer is nil, text will be copied unmodified (but paragraph format WILL be extended from the start of paragraph, removing subsequent paragraph formatting ).
+// Fields (MERGEFIELD, DATE, PAGE, ...) are preserved as long as the Replacer leaves their result unchanged.
 // If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
-func ModifyText(sourceFilePath string, replace Replacer, targetFilePath string) error {
+// Pictures can also be replaced, see WithImages.
+func ModifyText(sourceFilePath string, replace Replacer, targetFilePath string, opts ...Option) error {
+	return defaultProcessor().ModifyText(sourceFilePath, replace, targetFilePath, opts...)
+}
+
+// Same as ModifyText, with the settings of the Processor.
+func (p *Processor) ModifyText(sourceFilePath string, replace Replacer, targetFilePath string, opts ...Option) error {
 	if targetFilePath == "" {
 		targetFilePath = sourceFilePath
 	}
-	if VERBOSE {
+	if p.Verbose {
 		fmt.Println("Modifying : ", sourceFilePath, "-->", targetFilePath)
 	}
-	in, err := os.ReadFile(sourceFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %v", err)
-	}
-	var out []byte
-	out, err = ModifyTextBytes(in, replace)
-	if err != nil {
-		return fmt.Errorf("failed to modify text: %v", err)
-	}
-	os.WriteFile(targetFilePath, out, 0644)
-	return nil
+	return modifyFile(sourceFilePath, targetFilePath, "modify text", func(in []byte) ([]byte, error) {
+		return p.ModifyTextBytes(in, replace, opts...)
+	})
 }
 
 // All text from the sourceBytes is modified by applying the Replacer.
 // Before calling Replacer, the whole paragraph is collected as a single text, even if split on multiple runs.
 // Replacer is called paragraph by paragraph. It is never called on empty paragraphs.
 // If the Replacer is nil, text will be copied unmodified (but paragraph format WILL be extended from the start of paragraph, removing subsequent paragraph formatting).
-func ModifyTextBytes(sourceBytes []byte, replace Replacer) ([]byte, error) {
+// Fields (MERGEFIELD, DATE, PAGE, ...) are preserved as long as the Replacer leaves their result unchanged.
+// Pictures can also be replaced, see WithImages.
+func ModifyTextBytes(sourceBytes []byte, replace Replacer, opts ...Option) ([]byte, error) {
+	return defaultProcessor().ModifyTextBytes(sourceBytes, replace, opts...)
+}
+
+// Same as ModifyTextBytes, with the settings of the Processor.
+func (p *Processor) ModifyTextBytes(sourceBytes []byte, replace Replacer, opts ...Option) ([]byte, error) {
+	return p.modifyTextBytes(sourceBytes, replace, nil, opts)
+}
+
+// Modify the text with either the Replacer, or the ContextReplacer if not nil.
+func (p *Processor) modifyTextBytes(sourceBytes []byte, replace Replacer, contextual ContextReplacer, opts []Option) ([]byte, error) {
+
+	conf := newConfig(opts)
+	session := p.session() // settings changed by the templates only apply to this document
 
 	// Open the .docx (which is a zip file)
-	docxFile, err := zip.NewReader(bytes.NewReader(sourceBytes), int64(len(sourceBytes)))
+	pkg, err := openPackage(sourceBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open input bytes: %v", err)
 	}
+	pkg.verbose = session.Verbose
 	// defer docxFile.Close()
 
 	// default replace function, no change.
@@ -59,232 +71,392 @@ func ModifyTextBytes(sourceBytes []byte, replace Replacer) ([]byte, error) {
 		replace = func(_, s string) []string { return []string{s} }
 	}
 
-	// Prepare a buffer to store the modified .docx content
-	var buffer bytes.Buffer
-	zipWriter := zip.NewWriter(&buffer)
-
-	// Locate the document.xml and headers/footers files
-	var documentContent []byte
-	for _, file := range docxFile.File {
-		fname := file.Name
-		if containerPattern.MatchString(fname) {
-			if VERBOSE {
-				fmt.Println("Processing", fname)
-			}
-			documentContent, err = readFile(file)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read %s: %v", fname, err)
-			}
-			err = processContent(fname, documentContent, replace, zipWriter)
-			if err != nil {
-				return nil, err
-			}
-			continue // to next file container ...
-		}
-		// Copy other files unmodified into the new .docx
-		if err := copyFileToZip(zipWriter, file); err != nil {
-			return nil, fmt.Errorf("failed to copy file: %v", err)
+	// Process the selected containers (document.xml, headers/footers, ...), copy other files unmodified.
+	report := conf.errorReport()
+	out, err := pkg.rewrite(conf.parts, func(fname string, root *xnode) error {
+		if err := processContent(pkg, fname, root, replace, contextual, session, report, conf.markup); err != nil {
+			return err
 		}
+		return pkg.replaceImages(fname, root, conf.images)
+	})
+	if err == nil {
+		err = report.err()
 	}
-
-	// Close the zip writer
-	if err := zipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close zip writer: %v", err)
+	if err != nil {
+		return nil, err
 	}
-
-	// return
-	return buffer.Bytes(), nil
+	return out, nil
 }
 
-// process either the actual document.xml or the footer/header(s)
-func processContent(filename string, documentContent []byte, replace Replacer, zipWriter *zip.Writer) error {
-
-	if documentContent == nil {
-		return fmt.Errorf("%s not found in the docx file", filename)
+// process either the actual document.xml or the footer/header(s).
+// Template errors are collected in the report, if not nil. An error is returned when the processing should stop (fail fast).
+// The paragraphs are submitted to the ContextReplacer instead of the Replacer, if it is not nil.
+// The inline markup of the replaced text is converted into formatted runs if markup is true.
+func processContent(pkg *docxPackage, filename string, root *xnode, replace Replacer, contextual ContextReplacer, proc *Processor, report *errorReport, markup bool) error {
+	md := newModifier(pkg, filename, root, replace, proc)
+	md.contextual = contextual
+	md.markup = markup
+	md.reportErrors(report, root)
+	md.processParagraphs(root)
+	if proc.Verbose {
+		md.debug("Finished processing ...", filename)
 	}
-
-	cd := newCustDecoder(documentContent, replace)
-	cd.container = filename
-	cd.processParagraphs()
-	if VERBOSE {
-		cd.debug("Finished processing ...", filename)
-	}
-	modifiedXML, err := cd.result()
-	if err != nil {
-		return fmt.Errorf("failed to process %s: %v", filename, err)
-	}
-
-	// Add the modified xxx.xml back into the new .docx archive
-	writer, err := zipWriter.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to add modified %s to docx: %v", filename, err)
-	}
-	_, err = writer.Write(modifiedXML)
-	if err != nil {
-		return fmt.Errorf("failed to write modified %s: %v", filename, err)
+	if report.stopped() {
+		return report.err()
 	}
 	return nil
 }
 
-type custDecoder struct {
-	dec          *xml.Decoder
-	input        []byte   // initial doc content, unchanged
-	container    string   // current container being processed ("word/document.xm", "word/footer1.xml", ...)
-	res          [][]byte // result afeter processing
-	replace      Replacer // replacer function
-	lastSaved    int64    // index of last saved byte, index from input byte slice
-	err          error    // last error
-	rcontent     []byte   // agrregated text content of all runs from the same paragraph
-	curPara      int      // index of the the current paragraph start within res. Used to destroy entire paragraph upon request.
-	firstRunText int      // contains res index of first run text placeholder
-
+// Create a modifier for the container tree of the package (that may be nil), with the settings of the processor.
+func newModifier(pkg *docxPackage, filename string, root *xnode, replace Replacer, proc *Processor) *modifier {
+	if pkg != nil {
+		pkg.changes.useDrawingIDs(root)
+	}
+	return &modifier{pkg: pkg, container: filename, replace: replace, proc: proc}
 }
 
-func newCustDecoder(documentContent []byte, replacer Replacer) *custDecoder {
-	return &custDecoder{
-		input:        documentContent,
-		dec:          xml.NewDecoder(bytes.NewReader(documentContent)),
-		res:          make([][]byte, 1, 200), // ensure starts with empty string ...
-		replace:      replacer,
-		lastSaved:    -1,
-		err:          nil,
-		firstRunText: -1,
-		rcontent:     nil,
-		curPara:      -1,
+// Report the template errors found in the replaced text to r (see checkErrors), if not nil.
+// Paragraphs are identified by their index in the tree, before any modification.
+func (md *modifier) reportErrors(r *errorReport, root *xnode) {
+	md.report = r
+	if r == nil {
+		return
+	}
+	md.index = make(map[*xnode]int)
+	for i, p := range root.paragraphs() {
+		md.index[p] = i
 	}
 }
 
-// Get transformed result as a byte slice
-func (cd *custDecoder) result() ([]byte, error) {
-	cd.copy()
-	fr := bytes.Join(cd.res, nil)
-	//fmt.Println("Final result \n", (string)(fr))
-	return fr, cd.err
+// modifier applies a Replacer to the paragraphs of a container tree.
+type modifier struct {
+	pkg        *docxPackage    // package being modified, where new relationships and parts are added
+	container  string          // current container being processed ("word/document.xml", "word/footer1.xml", ...)
+	replace    Replacer        // replacer function
+	contextual ContextReplacer // replacer function receiving the paragraph context, used instead of replace if not nil
+	proc       *Processor      // settings, that the replaced text may change for the rest of the document
+	report     *errorReport    // template errors, when they are reported instead of inserted in the document
+	index      map[*xnode]int  // index of the paragraphs in the source tree, to report errors
+	markup     bool            // convert the inline markup of the replaced text into formatted runs (see WithMarkup)
 }
 
-// Copy the newly parsed content of the original docx to the result up to the last token parsed, included.
-func (cd *custDecoder) copy() {
-	if next := cd.dec.InputOffset(); cd.lastSaved+1 < next { // next points to the start of the next token never parsed ...
-		cd.res = append(cd.res, cd.input[cd.lastSaved+1:next])
-		cd.lastSaved = next - 1
-	}
+// replacement is the result of the Replacer for a given paragraph.
+type replacement struct {
+	para   *xnode   // paragraph to modify
+	paras  []string // replaced text, one string per resulting paragraph
+	remove bool     // remove the paragraph if paras is empty, as set when the replacer was called
 }
 
-// look for paragraphs
-func (cd *custDecoder) processParagraphs() {
+// Apply the replacer to all the paragraphs of the tree.
+// The replacer is called on every paragraph first, in document order, then the results are applied.
+// Paragraphs of text boxes are processed as any other paragraph, but the copies of the text boxes
+// found in alternate content fallbacks are not submitted to the replacer :
+// they receive the same replacement as the corresponding paragraph of the preferred choice.
+func (md *modifier) processParagraphs(root *xnode) {
 
-	var tok xml.Token
+	var todo []replacement
+	done := make(map[*xnode]replacement) // replaced paragraphs, with their result
+	var contexts map[*xnode]ParagraphContext
+	if md.contextual != nil {
+		contexts = md.paragraphContexts(root)
+	}
 
-	for tok, cd.err = cd.dec.Token(); cd.err == nil; tok, cd.err = cd.dec.Token() {
-		cd.copy() // immediately copy token in a separate res element
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "p" && t.Name.Space == NAMESPACE {
-				cd.curPara = len(cd.res) - 1 // mark para start, used to truncate later the current paragraph if so desired
-				cd.processRuns()
+	root.walk(func(n *xnode) bool {
+		switch {
+		case md.report.stopped():
+			return false
+		case n.is("p"):
+			if len(paragraphTexts(n, acceptedView)) > 0 { // make sure we saw at least a run with text !
+				var paras []string
+				if text := md.proc.reading + paragraphText(n, acceptedView); md.contextual != nil {
+					paras = md.contextual(contexts[n], text)
+				} else {
+					paras = md.replace(md.container, text)
+				}
+				paras = md.checkErrors(n, md.proc.applySettings(md.replaced(paras)))
+				done[n] = replacement{n, paras, md.proc.RemoveEmptyParagraph}
+				todo = append(todo, done[n])
 			}
+		case n.isFallback():
+			todo = append(todo, md.replay(n, done)...)
+			return false
 		}
-	}
+		return true
+	})
 
-	if cd.err == io.EOF { // ignore EOF, it's a normal ending here.
-		cd.err = nil
-	}
+	md.apply(todo)
 }
 
-// process runs, until end of paragraph
-// starts with para on top of res.
-func (cd *custDecoder) processRuns() {
-
-	var tok xml.Token
+// Get the replaced text of a paragraph. The template Replacer returns plain text, followed, beyond its length,
+// by the state of its reader and by the text marked with its errors and settings (see Processor.tplResult).
+// The state prefixes the text of the next paragraphs submitted to the replacer, until the reader is back to its initial state.
+// The marked text is returned instead of the plain text, unless the plain text was changed since (by a wrapping replacer).
+func (md *modifier) replaced(paras []string) []string {
+	full := paras[:cap(paras)]
+	if len(full) == len(paras) {
+		return paras
+	}
+	args, found := readerState(full[len(paras)])
+	if !found {
+		return paras
+	}
+	md.proc.reading = ""
+	if len(args) > 0 {
+		md.proc.reading = full[len(paras)]
+	}
+	if marked := full[len(paras)+1:]; slices.Equal(plainText(marked), paras) {
+		return marked
+	}
+	return paras
+}
 
-	// reset run text capture, since we are starting a new paragraph ...
-	cd.rcontent = nil
-	cd.firstRunText = -1
+// Apply the replacements, in reverse document order, so that text boxes are modified before their anchor paragraph is duplicated.
+func (md *modifier) apply(todo []replacement) {
+	for i := len(todo) - 1; i >= 0; i-- {
+		md.insert(todo[i])
+	}
+}
 
-	for tok, cd.err = cd.dec.Token(); cd.err == nil; tok, cd.err = cd.dec.Token() {
-		cd.copy() // immediately copy current element
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				cd.processText()
-			}
-		case xml.EndElement:
-			if t.Name.Local == "p" && t.Name.Space == NAMESPACE {
-				if cd.firstRunText >= 0 { // make sure we saw at least a run !
-					cd.insert(cd.replace(cd.container, (string)(cd.rcontent)))
-				}
-				return
-			}
+// Get the replacements for the paragraphs of an alternate content fallback,
+// by replaying the results of the paragraphs of the preferred choice, in the same order.
+// If the choice and the fallback do not have the same number of paragraphs, the fallback is left untouched.
+func (md *modifier) replay(fallback *xnode, done map[*xnode]replacement) (res []replacement) {
+	choice := fallback.parent.childNS(mcNamespace, "Choice")
+	if choice == nil {
+		return nil
+	}
+	cps, fps := choice.paragraphs(), fallback.paragraphs()
+	if len(cps) != len(fps) {
+		return nil
+	}
+	for i, fp := range fps {
+		if r, ok := done[cps[i]]; ok && len(paragraphTexts(fp, acceptedView)) > 0 {
+			r.para = fp
+			res = append(res, r)
 		}
 	}
+	return res
 }
 
 // Insert provided text in paragraph.
-// If slice is empty, current paragraph is discarded (unless discardflag is false)
+// The text is saved in the first text element of the paragraph, subsequent text elements are emptied.
+// If slice is empty, current paragraph is discarded (unless the remove flag was false when the replacer was called,
+// or the paragraph is the last one of a table cell, that Word requires).
 // If slice has more than 1 element, current paragraph is duplicated as needed.
-// When the function is called, an entire paraggraph should be available in res.
-func (cd *custDecoder) insert(paras []string) {
-	defer cd.debug("after paragragrph insertions")
+func (md *modifier) insert(r replacement) {
+	defer md.debug("after paragraph insertions")
+	p, paras := r.para, r.paras
+	if len(paras) == 0 {
+		if r.remove && !lastInCell(p) {
+			p.remove() // destroy the paragraph
+			return
+		}
+		paras = []string{""} // make sure we have something to insert
+	}
+	if md.markup {
+		paras = slices.Clone(paras)
+		for i, s := range paras {
+			paras[i] = parseMarkup(s)
+		}
+	}
+	if slices.ContainsFunc(paras, hasInline) {
+		md.insertContent(r.remove, p, paras)
+		return
+	}
+	md.setParagraphText(p, paras[0])
+	// duplicate paragraph for the following strings
+	prev := p
+	for _, s := range paras[1:] {
+		dup := p.clone()
+		md.setParagraphText(dup, s)
+		prev.insertAfter(dup)
+		prev = dup
+	}
+}
+
+// Insert the paragraphs and tables of a Content, as insert does for plain strings.
+// Tables preceding the first paragraph are inserted before the replaced paragraph, that is removed
+// if the content only has tables (unless the remove flag was false, or it is the last one of a table cell).
+func (md *modifier) insertContent(remove bool, p *xnode, paras []string) {
+	b := newBlockBase(p)
+	for len(paras) > 0 && tableArgs(paras[0]) != nil {
+		p.insertBefore(md.table(tableArgs(paras[0]), b))
+		paras = paras[1:]
+	}
 	if len(paras) == 0 {
-		if REMOVE_EMPTY_PARAGRAPH {
-			cd.res = cd.res[:cd.curPara]            // destroy the paragraph, the last copy was made for </p>
-			cd.lastSaved = cd.dec.InputOffset() - 1 // saving will resume at the tag following the paraggraph
+		if remove && !lastInCell(p) {
+			p.remove()
 			return
+		}
+		paras = []string{""}
+	}
+	md.setBlockText(p, paras[0], b)
+	prev := p
+	for _, s := range paras[1:] {
+		var next *xnode
+		if args := tableArgs(s); args != nil {
+			next = md.table(args, b)
 		} else {
-			paras = []string{""} // make sure we have something to insert
+			next = p.clone()
+			md.setBlockText(next, s, b)
 		}
+		prev.insertAfter(next)
+		prev = next
 	}
-	cd.res[cd.firstRunText] = xmlEscape([]byte(paras[0])) // save escapes 1st content to first run
-	if len(paras) == 1 {
-		return // we're done
-	}
-	// else, duplicate paragph
-	dup := cd.res[cd.curPara:]
-	cd.res = append(cd.res, dup...)
-	// update indexes
-	cd.curPara = cd.curPara + len(dup)
-	cd.firstRunText = cd.firstRunText + len(dup)
-	// recurse
-	cd.insert(paras[1:])
 }
 
-// process text within a run, until end of run
-func (cd *custDecoder) processText() {
-	var tok xml.Token
-	for tok, cd.err = cd.dec.Token(); cd.err == nil; tok, cd.err = cd.dec.Token() {
-		cd.copy() // copy captured element
-		switch t := tok.(type) {
-		case xml.StartElement:
-			if t.Name.Local == "t" && t.Name.Space == NAMESPACE {
-				if cd.firstRunText < 0 { // no run was seen in this paragraph yet, prepare this run for saving aggregated text.
-					cd.res = append(cd.res, []byte{}) // add empty place holder for future aggregated text
-					cd.firstRunText = len(cd.res) - 1 // remember index of empty place holder !
-				}
-				cd.processTextContent()
-			}
-		case xml.EndElement:
-			if t.Name.Local == "r" && t.Name.Space == NAMESPACE {
-				return
+// Check if the paragraph is the only paragraph of a table cell.
+func lastInCell(p *xnode) bool {
+	if p.parent == nil || !p.parent.is("tc") {
+		return false
+	}
+	for _, c := range p.parent.children {
+		if c != p && c.is("p") {
+			return false
+		}
+	}
+	return true
+}
+
+// Save the text in the first text element of the paragraph, empty the other text elements.
+// Fields are preserved if their results are found, in the same order, in the new text : the text around each field
+// is then saved in the text elements around it. Otherwise, the fields are converted into plain text.
+// Inline objects of the text (see Link) are rendered after the run of the text element where they are saved.
+func (md *modifier) setParagraphText(p *xnode, text string) {
+	if all := paragraphFields(p); len(all) > 0 {
+		if md.setTextAroundFields(p, all, text) {
+			return
+		}
+		for _, f := range all {
+			if f.parent == nil {
+				f.unwrap(all)
 			}
 		}
 	}
+	for i, t := range paragraphTexts(p, acceptedView) {
+		if i == 0 {
+			md.setRichText(t, text)
+		} else {
+			t.setText("")
+		}
+	}
+}
+
+// Get the original text following the top level field i of a paragraph, given the groups of plain text elements around the fields.
+func textAfterField(groups [][]*xnode, fields []*field, i int) string {
+	var res strings.Builder
+	for j := i + 1; j < len(groups); j++ {
+		for _, t := range groups[j] {
+			res.WriteString(t.textContent())
+		}
+		if j < len(fields) {
+			res.WriteString(fields[j].result())
+		}
+	}
+	return res.String()
 }
 
-// process text. We just read the <t> tag ...
-func (cd *custDecoder) processTextContent() {
-	var tok xml.Token
-	for tok, cd.err = cd.dec.Token(); cd.err == nil; tok, cd.err = cd.dec.Token() {
-		switch t := tok.(type) {
-		case xml.CharData: // that will not be copied, only aggregated, to be saved later in the placeholder.
-			cd.rcontent = append(cd.rcontent, t...)
-			cd.lastSaved = cd.dec.InputOffset() - 1
-		case xml.EndElement:
-			cd.copy() // copy the end tag, whatever it is
-			if t.Name.Local == "t" && t.Name.Space == NAMESPACE {
-				return
+// Get the fields that start and end in the paragraph, excluding those of nested paragraphs (text boxes).
+// Fields spanning several paragraphs (such as tables of contents) are ignored, their results are processed as plain text.
+func paragraphFields(p *xnode) (res []*field) {
+	for _, f := range findFields(p) {
+		if first, last := f.bounds(); f.closed() && first.ancestor("p") == p && last.ancestor("p") == p {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// Save the text around the top level fields of the paragraph, keeping the fields unchanged.
+// Returns false, without any change, if the field results cannot be found in the text, in the same order.
+func (md *modifier) setTextAroundFields(p *xnode, all []*field, text string) bool {
+	var fields []*field // top level fields
+	for _, f := range all {
+		if f.parent == nil {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return false
+	}
+
+	// group the plain text elements : before the first field, between fields, after the last field
+	order := newDocOrder(p)
+	inField := make(map[*xnode]bool)
+	for _, f := range all {
+		for _, t := range f.results {
+			inField[t] = true
+		}
+	}
+	groups := make([][]*xnode, len(fields)+1)
+	for _, t := range paragraphTexts(p, acceptedView) {
+		if inField[t] {
+			continue
+		}
+		i := 0
+		for i < len(fields) {
+			if first, _ := fields[i].bounds(); order.pos[first] > order.pos[t] {
+				break
+			}
+			i++
+		}
+		groups[i] = append(groups[i], t)
+	}
+
+	// locate the field results in the new text
+	segments := make([]string, len(fields)+1)
+	rest := text
+	for i, f := range fields {
+		var original strings.Builder
+		for _, t := range groups[i] {
+			original.WriteString(t.textContent())
+		}
+		r, idx := f.result(), 0
+		switch {
+		case strings.HasPrefix(rest, original.String()+r):
+			idx = original.Len()
+		case r != "":
+			if idx = strings.Index(rest, r); idx < 0 {
+				return false
 			}
 		default:
-			cd.copy() // by default, we copy everything, except chardata !
+			// an empty result is located by the original text following the field
+			following := textAfterField(groups, fields, i)
+			if !strings.HasSuffix(rest, following) {
+				return false
+			}
+			idx = len(rest) - len(following)
+		}
+		segments[i], rest = rest[:idx], rest[idx+len(r):]
+	}
+	segments[len(fields)] = rest
+
+	// save the text segments
+	for i, s := range segments {
+		if len(groups[i]) > 0 {
+			for j, t := range groups[i] {
+				if j == 0 {
+					md.setRichText(t, s)
+				} else {
+					t.setText("")
+				}
+			}
+			continue
+		}
+		if s == "" {
+			continue
+		}
+		f := fields[min(i, len(fields)-1)]
+		run, err := parseFragment(`<w:r>` + f.runProperties() + `<w:t xml:space="preserve"></w:t></w:r>`)
+		if err != nil {
+			panic("invalid run fragment : " + err.Error()) // should never happen
+		}
+		if first, last := f.bounds(); i < len(fields) {
+			first.insertBefore(run...)
+		} else {
+			last.insertAfter(run...)
 		}
+		md.setRichText(run[0].child("t"), s)
 	}
+	return true
 }
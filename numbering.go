@@ -0,0 +1,423 @@
+package mydocx
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numLevel describes a level of a list definition (w:lvl).
+type numLevel struct {
+	start   int    // first value
+	format  string // number format : decimal, lowerRoman, upperLetter, bullet, ...
+	text    string // level text, with %1 ... %9 placeholders for the current values of the levels
+	suffix  string // what follows the label : tab, space or nothing
+	restart int    // restart after a higher level was used : -1 by default, 0 never, n after level n (1 based)
+	legal   bool   // display all levels as decimal numbers
+	style   string // paragraph style linked to the level, if any
+}
+
+// numInstance describes a numbering instance (w:num), referenced by the paragraphs.
+type numInstance struct {
+	abstract  string            // id of the abstract list definition
+	starts    map[int]int       // start overrides, by level
+	overrides map[int]*numLevel // level overrides, by level
+}
+
+// numCounters holds the current values of the levels of an abstract list definition.
+type numCounters struct {
+	value [9]int
+	set   [9]bool // level was used since its last restart
+}
+
+// numbering computes the list labels of paragraphs, in document order.
+// Counters are shared by the numbering instances of the same abstract list definition.
+type numbering struct {
+	styles    *styleSheet
+	abstracts map[string]map[int]*numLevel // levels of the abstract list definitions, by id
+	links     map[string]string            // numbering style linked by abstract list definitions
+	nums      map[string]*numInstance      // numbering instances, by id
+	counters  map[string]*numCounters      // counters, by abstract id
+	used      map[string]bool              // numbering instances already used, their start overrides were applied
+}
+
+// Load the numbering definitions of the package.
+// The numbering is empty if the package has no numbering part.
+func (pkg *docxPackage) numbering() (*numbering, error) {
+	ss, err := pkg.styleSheet()
+	if err != nil {
+		return nil, err
+	}
+	nb := &numbering{
+		styles:    ss,
+		abstracts: make(map[string]map[int]*numLevel),
+		links:     make(map[string]string),
+		nums:      make(map[string]*numInstance),
+	}
+	nb.reset()
+	name, err := pkg.relatedPart(numberingRelType, "word/numbering.xml")
+	if err != nil {
+		return nil, err
+	}
+	content, err := pkg.read(name)
+	if err != nil || content == nil {
+		return nb, err
+	}
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range root.findAll("abstractNum") {
+		id, _ := c.attrValue(NAMESPACE, "abstractNumId")
+		levels := make(map[int]*numLevel)
+		for _, l := range c.findAll("lvl") {
+			ilvl, lvl := parseNumLevel(l)
+			levels[ilvl] = lvl
+		}
+		nb.abstracts[id] = levels
+		if link := c.child("numStyleLink"); link != nil {
+			nb.links[id] = link.val()
+		}
+	}
+	for _, c := range root.findAll("num") {
+		id, _ := c.attrValue(NAMESPACE, "numId")
+		num := &numInstance{starts: make(map[int]int), overrides: make(map[int]*numLevel)}
+		if a := c.child("abstractNumId"); a != nil {
+			num.abstract = a.val()
+		}
+		for _, o := range c.findAll("lvlOverride") {
+			ilvl, _ := strconv.Atoi(attrOf(o, "ilvl"))
+			if s := o.child("startOverride"); s != nil {
+				num.starts[ilvl], _ = strconv.Atoi(s.val())
+			}
+			if l := o.child("lvl"); l != nil {
+				_, num.overrides[ilvl] = parseNumLevel(l)
+			}
+		}
+		nb.nums[id] = num
+	}
+	return nb, nil
+}
+
+// listDefinitions holds the numbering instances used by the lists of a Content, loaded once per package.
+type listDefinitions struct {
+	nb       *numbering        // numbering definitions of the package
+	part     string            // name of the numbering part
+	defaults map[string]string // first numbering instance of each list kind (bullet, number)
+	last     int               // last numbering instance id used
+}
+
+// Get the numbering instances used by the lists of a Content.
+func (pkg *docxPackage) listDefinitions() (*listDefinitions, error) {
+	if pkg.lists != nil {
+		return pkg.lists, nil
+	}
+	nb, err := pkg.numbering()
+	if err != nil {
+		return nil, err
+	}
+	part, err := pkg.relatedPart(numberingRelType, "word/numbering.xml")
+	if err != nil {
+		return nil, err
+	}
+	ld := &listDefinitions{nb: nb, part: part, defaults: make(map[string]string)}
+	ids := make([]int, 0, len(nb.nums))
+	for id := range nb.nums {
+		n, _ := strconv.Atoi(id)
+		ids = append(ids, n)
+		ld.last = max(ld.last, n)
+	}
+	sort.Ints(ids)
+	for _, n := range ids {
+		id := strconv.Itoa(n)
+		if k := ld.kind(id); k != "" && ld.defaults[k] == "" {
+			ld.defaults[k] = id
+		}
+	}
+	pkg.lists = ld
+	return ld, nil
+}
+
+// Get the list kind of a numbering instance, from its first level : bullet, number (decimal), or an empty string.
+func (ld *listDefinitions) kind(numID string) string {
+	switch lvl := ld.nb.level(numID, 0); {
+	case lvl == nil:
+		return ""
+	case lvl.format == "bullet":
+		return "bullet"
+	case lvl.format == "decimal":
+		return "number"
+	}
+	return ""
+}
+
+// Add a numbering instance of the list definition of an existing instance, restarting at the start of its first level.
+// The new instance is added to the numbering part while rewriting, and its id is returned.
+func (pkg *docxPackage) restartNumbering(numID string) string {
+	ld := pkg.lists
+	num := ld.nb.nums[numID]
+	start := 0
+	if lvl := ld.nb.level(numID, 0); lvl != nil {
+		start = lvl.start
+	}
+	ld.last++
+	id := strconv.Itoa(ld.last)
+	ld.nb.nums[id] = &numInstance{abstract: num.abstract, starts: map[int]int{0: start}, overrides: num.overrides}
+	pkg.changes.numbering = ld.part
+	pkg.changes.nums = append(pkg.changes.nums, fmt.Sprintf(`<w:num w:numId="%s"><w:abstractNumId w:val="%s"/>`+
+		`<w:lvlOverride w:ilvl="0"><w:startOverride w:val="%d"/></w:lvlOverride></w:num>`, id, xmlEscape([]byte(num.abstract)), start))
+	return id
+}
+
+// Get the value of a w: attribute, or an empty string.
+func attrOf(n *xnode, local string) string {
+	v, _ := n.attrValue(NAMESPACE, local)
+	return v
+}
+
+// Parse a w:lvl element.
+func parseNumLevel(l *xnode) (int, *numLevel) {
+	ilvl, _ := strconv.Atoi(attrOf(l, "ilvl"))
+	lvl := &numLevel{start: 0, format: "decimal", suffix: "tab", restart: -1}
+	for _, c := range l.children {
+		switch {
+		case c.is("start"):
+			lvl.start, _ = strconv.Atoi(c.val())
+		case c.is("numFmt"):
+			lvl.format = c.val()
+		case c.is("lvlText"):
+			lvl.text = c.val()
+		case c.is("suff"):
+			lvl.suffix = c.val()
+		case c.is("lvlRestart"):
+			lvl.restart, _ = strconv.Atoi(c.val())
+		case c.is("isLgl"):
+			lvl.legal = c.val() != "0" && c.val() != "false"
+		case c.is("pStyle"):
+			lvl.style = c.val()
+		}
+	}
+	return ilvl, lvl
+}
+
+// Reset all the counters, before processing a new container.
+func (nb *numbering) reset() {
+	nb.counters = make(map[string]*numCounters)
+	nb.used = make(map[string]bool)
+}
+
+// Get the abstract list definition of a numbering instance, following numbering style links.
+func (nb *numbering) abstract(numID string) string {
+	for i := 0; i < 3; i++ { // links are never nested deeper
+		num := nb.nums[numID]
+		if num == nil {
+			return ""
+		}
+		style, ok := nb.links[num.abstract]
+		if !ok {
+			return num.abstract
+		}
+		numID, _ = nb.styles.numbering(style)
+	}
+	return ""
+}
+
+// Get the definition of a level of a numbering instance, or nil.
+func (nb *numbering) level(numID string, ilvl int) *numLevel {
+	if num := nb.nums[numID]; num != nil && num.overrides[ilvl] != nil {
+		return num.overrides[ilvl]
+	}
+	return nb.abstracts[nb.abstract(numID)][ilvl]
+}
+
+// Get the numbering instance and the level of a paragraph, from its properties or from its style.
+// The numbering instance is empty if the paragraph is not numbered.
+func (nb *numbering) paragraphNumbering(p *xnode) (numID string, ilvl int) {
+	style := nb.styles.paragraphStyleID(p)
+	numID, ilvl = nb.styles.numbering(style)
+	if pPr := p.child("pPr"); pPr != nil {
+		if numPr := pPr.child("numPr"); numPr != nil {
+			n, l := numPrValues(numPr)
+			if n != "" {
+				numID = n
+			}
+			if l >= 0 {
+				ilvl = l
+			}
+		}
+	}
+	if numID == "" || numID == "0" { // numId 0 removes the numbering
+		return "", 0
+	}
+	if ilvl < 0 { // the level linked to the style, or else the first level
+		ilvl = 0
+		for i, lvl := range nb.abstracts[nb.abstract(numID)] {
+			if lvl.style == style {
+				ilvl = i
+			}
+		}
+	}
+	return numID, ilvl
+}
+
+// Compute the list label of the next paragraph, updating the counters.
+// Paragraphs must be submitted in document order.
+// Returns the label (eg : "4.2", "(b)", "•") and the text to display after it, or empty strings if the paragraph is not numbered.
+func (nb *numbering) next(p *xnode) (label string, suffix string) {
+	numID, ilvl := nb.paragraphNumbering(p)
+	if numID == "" || ilvl < 0 || ilvl > 8 {
+		return "", ""
+	}
+	lvl := nb.level(numID, ilvl)
+	if lvl == nil {
+		return "", ""
+	}
+	abstract := nb.abstract(numID)
+	cnt := nb.counters[abstract]
+	if cnt == nil {
+		cnt = new(numCounters)
+		nb.counters[abstract] = cnt
+	}
+	if !nb.used[numID] { // start overrides restart the list
+		nb.used[numID] = true
+		for i := range nb.nums[numID].starts {
+			if i >= 0 && i <= 8 {
+				cnt.set[i] = false
+			}
+		}
+	}
+
+	// increment the current level, restart the lower levels
+	if cnt.set[ilvl] {
+		cnt.value[ilvl]++
+	} else {
+		cnt.value[ilvl], cnt.set[ilvl] = nb.start(numID, ilvl), true
+	}
+	for i := ilvl + 1; i <= 8; i++ {
+		if l := nb.level(numID, i); l == nil || l.restart < 0 || (l.restart > 0 && ilvl < l.restart) {
+			cnt.set[i] = false
+		}
+	}
+
+	// format the level text
+	if lvl.format == "bullet" {
+		label = bulletText(lvl.text)
+	} else if lvl.format != "none" {
+		label = lvl.text
+		for i := 0; i <= ilvl; i++ {
+			value := cnt.value[i]
+			if !cnt.set[i] {
+				value = nb.start(numID, i)
+			}
+			format := "decimal"
+			if l := nb.level(numID, i); l != nil && !lvl.legal {
+				format = l.format
+			}
+			label = strings.ReplaceAll(label, "%"+strconv.Itoa(i+1), formatNumber(value, format))
+		}
+	}
+	if label != "" && lvl.suffix != "nothing" {
+		suffix = " " // tabs are rendered as a single space
+	}
+	return label, suffix
+}
+
+// Get the start value of a level of a numbering instance.
+func (nb *numbering) start(numID string, ilvl int) int {
+	if num := nb.nums[numID]; num != nil {
+		if s, ok := num.starts[ilvl]; ok {
+			return s
+		}
+	}
+	if l := nb.level(numID, ilvl); l != nil {
+		return l.start
+	}
+	return 1
+}
+
+// Symbol font characters commonly used as bullets, mapped to their unicode equivalent.
+var bulletSymbols = map[rune]string{
+	0xF0B7: "•", 0xF0A7: "▪", 0xF06E: "■", 0xF071: "❑", 0xF076: "❖", 0xF0D8: "➢", 0xF0FC: "✓", 0xF0E0: "➔", 0xF02D: "-",
+}
+
+// Get the text of a bullet, replacing symbol font characters by their unicode equivalent.
+func bulletText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch s, ok := bulletSymbols[r]; {
+		case ok:
+			sb.WriteString(s)
+		case r >= 0xF000 && r <= 0xF0FF: // other symbol font characters
+			sb.WriteString("•")
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// Format a number with a Word number format.
+// Unsupported formats are rendered as decimal numbers.
+func formatNumber(n int, format string) string {
+	switch format {
+	case "decimalZero":
+		if n < 10 && n >= 0 {
+			return "0" + strconv.Itoa(n)
+		}
+	case "upperRoman":
+		return strings.ToUpper(toRoman(n))
+	case "lowerRoman":
+		return toRoman(n)
+	case "upperLetter":
+		return strings.ToUpper(toLetter(n))
+	case "lowerLetter":
+		return toLetter(n)
+	case "ordinal":
+		return strconv.Itoa(n) + ordinalSuffix(n)
+	case "none":
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// Convert a number into lower case roman numerals. Non positive numbers are rendered as decimal.
+func toRoman(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	var sb strings.Builder
+	for _, r := range []struct {
+		value int
+		text  string
+	}{{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"}} {
+		for ; n >= r.value; n -= r.value {
+			sb.WriteString(r.text)
+		}
+	}
+	return sb.String()
+}
+
+// Convert a number into lower case letters, as Word does : a ... z, aa ... zz, aaa ...
+// Non positive numbers are rendered as decimal.
+func toLetter(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	return strings.Repeat(string(rune('a'+(n-1)%26)), (n-1)/26+1)
+}
+
+// Get the english ordinal suffix of a number (st, nd, rd, th).
+func ordinalSuffix(n int) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return "th"
+	case n%10 == 1:
+		return "st"
+	case n%10 == 2:
+		return "nd"
+	case n%10 == 3:
+		return "rd"
+	}
+	return "th"
+}
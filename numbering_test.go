@@ -0,0 +1,112 @@
+package mydocx
+
+import (
+	"fmt"
+	"testing"
+)
+
+// list definitions : multi level clauses, bullets, and roman numbering linked to the Heading1 style
+const testNumbering = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering ` + testNamespaces + `>` +
+	`<w:abstractNum w:abstractNumId="0">` +
+	`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>` +
+	`<w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1.%2"/></w:lvl>` +
+	`<w:lvl w:ilvl="2"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="(%3)"/><w:suff w:val="space"/></w:lvl>` +
+	`</w:abstractNum>` +
+	`<w:abstractNum w:abstractNumId="1"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/><w:lvlText w:val="` + "" + `"/></w:lvl></w:abstractNum>` +
+	`<w:abstractNum w:abstractNumId="2"><w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="upperRoman"/><w:lvlText w:val="%1."/><w:pStyle w:val="Heading1"/></w:lvl></w:abstractNum>` +
+	`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>` +
+	`<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>` +
+	`<w:num w:numId="3"><w:abstractNumId w:val="0"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="5"/></w:lvlOverride></w:num>` +
+	`<w:num w:numId="4"><w:abstractNumId w:val="2"/></w:num>` +
+	`</w:numbering>`
+
+const testStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles ` + testNamespaces + `>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:numPr><w:numId w:val="4"/></w:numPr><w:outlineLvl w:val="0"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>` +
+	`</w:styles>`
+
+// Build a paragraph with the provided paragraph properties.
+func testStyledPara(pPr string, text string) string {
+	return fmt.Sprintf(`<w:p><w:pPr>%s</w:pPr><w:r><w:t xml:space="preserve">%s</w:t></w:r></w:p>`, pPr, text)
+}
+
+// Build a numbered paragraph.
+func testListPara(numID, ilvl int, text string) string {
+	return testStyledPara(fmt.Sprintf(`<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, ilvl, numID), text)
+}
+
+func TestNumbering(t *testing.T) {
+
+	body := testListPara(1, 0, "Scope") +
+		testListPara(1, 1, "Detail") +
+		testListPara(1, 2, "Item") +
+		testListPara(1, 2, "Item") +
+		testListPara(1, 1, "Next") +
+		testListPara(1, 0, "Second") +
+		testListPara(1, 1, "Restarted") +
+		testListPara(2, 0, "Bullet") +
+		testListPara(3, 0, "Overridden") +
+		testPara("Not numbered") +
+		testStyledPara(`<w:pStyle w:val="Heading1"/>`, "Heading") +
+		testStyledPara(`<w:pStyle w:val="Heading1"/>`, "Heading") +
+		testStyledPara(`<w:pStyle w:val="Heading1"/><w:numPr><w:numId w:val="0"/></w:numPr>`, "Plain heading")
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:     testContentTypes,
+		"word/document.xml":  testDocument(body),
+		"word/numbering.xml": testNumbering,
+		"word/styles.xml":    testStyles,
+	})
+
+	want := []string{"1. Scope", "1.1 Detail", "(a) Item", "(b) Item", "1.2 Next", "2. Second", "2.1 Restarted", "• Bullet",
+		"5. Overridden", "Not numbered", "I. Heading", "II. Heading", "Plain heading"}
+
+	pp, err := ExtractTextBytes(docx, WithNumbering())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := pp["word/document.xml"]
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("paragraph %d : got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// labels are not extracted by default
+	pp, err = ExtractTextBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp["word/document.xml"][0] != "Scope" {
+		t.Errorf("unexpected label without WithNumbering : %q", pp["word/document.xml"][0])
+	}
+}
+
+func TestNumberFormats(t *testing.T) {
+	tests := []struct {
+		n      int
+		format string
+		want   string
+	}{
+		{4, "decimal", "4"},
+		{4, "decimalZero", "04"},
+		{1994, "upperRoman", "MCMXCIV"},
+		{9, "lowerRoman", "ix"},
+		{2, "lowerLetter", "b"},
+		{28, "upperLetter", "BB"},
+		{22, "ordinal", "22nd"},
+		{12, "ordinal", "12th"},
+		{3, "unknownFormat", "3"},
+	}
+	for _, tt := range tests {
+		if got := formatNumber(tt.n, tt.format); got != tt.want {
+			t.Errorf("formatNumber(%d, %q) = %q, want %q", tt.n, tt.format, got, tt.want)
+		}
+	}
+}
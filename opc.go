@@ -0,0 +1,457 @@
+package mydocx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Parts selects which OPC parts (containers) of a docx package are processed.
+// Parts are identified by their content type, as declared in [Content_Types].xml, not by their file name.
+// Parts values can be combined, eg : PartBody|PartHeaders.
+type Parts uint
+
+const (
+	// Main document body (word/document.xml)
+	PartBody Parts = 1 << iota
+	// Headers (word/header1.xml, ...)
+	PartHeaders
+	// Footers (word/footer1.xml, ...)
+	PartFooters
+	// Footnotes (word/footnotes.xml)
+	PartFootnotes
+	// Endnotes (word/endnotes.xml)
+	PartEndnotes
+	// Comments (word/comments.xml)
+	PartComments
+	// Glossary document, containing building blocks and autotext entries (word/glossary/document.xml)
+	PartGlossary
+
+	// Parts processed when no selection is provided : body, headers and footers.
+	DefaultParts = PartBody | PartHeaders | PartFooters
+	// All the parts that contain paragraphs.
+	AllParts = PartBody | PartHeaders | PartFooters | PartFootnotes | PartEndnotes | PartComments | PartGlossary
+)
+
+// content types of the parts that contain text, mapped to the corresponding part selector.
+var partContentTypes = map[string]Parts{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml":     PartBody,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml":     PartBody,
+	"application/vnd.ms-word.document.macroEnabled.main+xml":                               PartBody,
+	"application/vnd.ms-word.template.macroEnabledTemplate.main+xml":                       PartBody,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml":            PartHeaders,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml":            PartFooters,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml":         PartFootnotes,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.endnotes+xml":          PartEndnotes,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.comments+xml":          PartComments,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.glossary+xml": PartGlossary,
+}
+
+// name of the content types part, at the root of the package.
+const contentTypesName = "[Content_Types].xml"
+
+// contentTypes is the content of the [Content_Types].xml part.
+type contentTypes struct {
+	Defaults []struct {
+		Extension   string `xml:"Extension,attr"`
+		ContentType string `xml:"ContentType,attr"`
+	} `xml:"Default"`
+	Overrides []struct {
+		PartName    string `xml:"PartName,attr"`
+		ContentType string `xml:"ContentType,attr"`
+	} `xml:"Override"`
+}
+
+// docxPackage gives access to the content of a docx (zip) package.
+type docxPackage struct {
+	zr      *zip.Reader
+	types   *contentTypes    // nil if the package has no [Content_Types].xml
+	changes packageChanges   // relationships and parts added while rewriting
+	lists   *listDefinitions // numbering instances of the lists of a Content, loaded on first use
+	verbose bool             // print the containers being processed, VERBOSE unless set from the settings of a Processor
+}
+
+// Open the docx package from its bytes, and load its content types.
+func openPackage(sourceBytes []byte) (*docxPackage, error) {
+	zr, err := zip.NewReader(bytes.NewReader(sourceBytes), int64(len(sourceBytes)))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &docxPackage{zr: zr, verbose: VERBOSE}
+	for _, file := range zr.File {
+		if file.Name == contentTypesName {
+			data, err := readFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", contentTypesName, err)
+			}
+			pkg.types = new(contentTypes)
+			if err = xml.Unmarshal(data, pkg.types); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %v", contentTypesName, err)
+			}
+			break
+		}
+	}
+	return pkg, nil
+}
+
+// Get the content type of the named part.
+// Overrides take precedence over the defaults declared for the file extension.
+// Returns an empty string if unknown.
+func (pkg *docxPackage) contentType(name string) string {
+	if pkg.types == nil {
+		return ""
+	}
+	for _, o := range pkg.types.Overrides {
+		if strings.EqualFold(strings.TrimPrefix(o.PartName, "/"), name) {
+			return o.ContentType
+		}
+	}
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	for _, d := range pkg.types.Defaults {
+		if strings.EqualFold(d.Extension, ext) {
+			return d.ContentType
+		}
+	}
+	return ""
+}
+
+// Get the kind of the named part, or 0 if the part does not contain text.
+// When the package has no content types, the kind is deduced from the file name.
+func (pkg *docxPackage) partKind(name string) Parts {
+	if pkg.types == nil {
+		switch {
+		case !containerPattern.MatchString(name):
+			return 0
+		case strings.HasPrefix(name, "word/header"):
+			return PartHeaders
+		case strings.HasPrefix(name, "word/footer"):
+			return PartFooters
+		default:
+			return PartBody
+		}
+	}
+	return partContentTypes[pkg.contentType(name)]
+}
+
+// Check if the named part should be processed, given the parts selection.
+func (pkg *docxPackage) selected(name string, parts Parts) bool {
+	return pkg.partKind(name)&parts != 0
+}
+
+// relationships is the content of a .rels part.
+type relationships struct {
+	Relationships []relationship `xml:"Relationship"`
+}
+
+// relationship is a single relationship from a source part to a target.
+type relationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr,omitempty"`
+}
+
+// Get the name of the relationship part for the named source part (eg : word/_rels/document.xml.rels).
+func relsName(name string) string {
+	dir, base := path.Split(name)
+	return dir + "_rels/" + base + ".rels"
+}
+
+// Get the content of the named part, or nil if it does not exist.
+func (pkg *docxPackage) read(name string) ([]byte, error) {
+	for _, file := range pkg.zr.File {
+		if file.Name == name {
+			return readFile(file)
+		}
+	}
+	return nil, nil
+}
+
+// Get the relationships of the named source part, keyed by relationship id.
+// Internal targets are resolved to the part names of the package.
+// Returns an empty map if the part has no relationships.
+func (pkg *docxPackage) rels(name string) (map[string]relationship, error) {
+	res := make(map[string]relationship)
+	data, err := pkg.read(relsName(name))
+	if err != nil || data == nil {
+		return res, err
+	}
+	rr := new(relationships)
+	if err = xml.Unmarshal(data, rr); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", relsName(name), err)
+	}
+	for _, r := range rr.Relationships {
+		if r.TargetMode != "External" {
+			if strings.HasPrefix(r.Target, "/") {
+				r.Target = strings.TrimPrefix(r.Target, "/")
+			} else {
+				r.Target = path.Join(path.Dir(name), r.Target)
+			}
+		}
+		res[r.ID] = r
+	}
+	return res, nil
+}
+
+// Get the names of the parts of the selected kinds, in zip order.
+func (pkg *docxPackage) names(parts Parts) []string {
+	var res []string
+	for _, file := range pkg.zr.File {
+		if pkg.selected(file.Name, parts) {
+			res = append(res, file.Name)
+		}
+	}
+	return res
+}
+
+// Rewrite the package into a new docx.
+// The selected containers are parsed and transformed in zip order, then written back with the other files,
+// that are copied unchanged, except for the relationships and the content types updated by the transformations.
+// New parts created by the transformations are added at the end of the package.
+func (pkg *docxPackage) rewrite(parts Parts, transform func(name string, root *xnode) error) ([]byte, error) {
+	return pkg.rewriteNames(pkg.names(parts), transform)
+}
+
+// Same as rewrite, but transforms the named containers, in the provided order.
+func (pkg *docxPackage) rewriteNames(names []string, transform func(name string, root *xnode) error) ([]byte, error) {
+
+	// transform the containers first, since they may add relationships and parts
+	files := make(map[string]*zip.File, len(pkg.zr.File))
+	for _, file := range pkg.zr.File {
+		files[file.Name] = file
+	}
+	transformed := make(map[string][]byte)
+	for _, fname := range names {
+		file := files[fname]
+		if file == nil {
+			continue
+		}
+		if pkg.verbose {
+			fmt.Println("Processing", fname)
+		}
+		content, err := readFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", fname, err)
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process %s: %v", fname, err)
+		}
+		if err = transform(fname, root); err != nil {
+			return nil, err
+		}
+		transformed[fname] = root.bytes()
+	}
+
+	// Prepare a buffer to store the modified .docx content
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	write := func(name string, data []byte) error {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to docx: %v", name, err)
+		}
+		if _, err = writer.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		return nil
+	}
+
+	written := make(map[string]bool)
+	for _, file := range pkg.zr.File {
+		fname := file.Name
+		written[fname] = true
+		data, ok := transformed[fname]
+		if !ok {
+			patched, err := pkg.patch(file)
+			if err != nil {
+				return nil, err
+			}
+			if patched == nil {
+				// Copy other files unmodified into the new .docx
+				if err := copyFileToZip(zipWriter, file); err != nil {
+					return nil, fmt.Errorf("failed to copy file: %v", err)
+				}
+				continue
+			}
+			data = patched
+		}
+		if err := write(fname, data); err != nil {
+			return nil, err
+		}
+	}
+
+	// add the relationship parts that did not exist, and the new parts
+	for _, source := range pkg.changes.sources {
+		if name := relsName(source); !written[name] {
+			data := []byte(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				pkg.changes.relsXML(source) + `</Relationships>`)
+			if err := write(name, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range pkg.changes.names {
+		if err := write(name, pkg.changes.parts[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Close the zip writer
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip writer: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// packageChanges holds the changes made to the package while rewriting its containers.
+type packageChanges struct {
+	rels      map[string][]relationship // new relationships, by source part
+	sources   []string                  // source parts with new relationships, in order
+	parts     map[string][]byte         // content of the new parts, by name
+	types     map[string]string         // content type of the new parts, by name
+	names     []string                  // names of the new parts, in order
+	media     map[string]string         // names of the new images, by content
+	drawing   int                       // last drawing id used
+	nums      []string                  // new numbering instances (w:num), added to the numbering part
+	numbering string                    // name of the numbering part
+}
+
+// Add a relationship from the source part to the target, and return its id.
+// Internal targets are part names of the package, they are made relative to the source part.
+func (pkg *docxPackage) addRelationship(source, relType, target string, external bool) (string, error) {
+	existing, err := pkg.rels(source)
+	if err != nil {
+		return "", err
+	}
+	pc := &pkg.changes
+	used := func(id string) bool {
+		if _, ok := existing[id]; ok {
+			return true
+		}
+		for _, r := range pc.rels[source] {
+			if r.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+	id := ""
+	for i := len(existing) + len(pc.rels[source]) + 1; id == "" || used(id); i++ {
+		id = fmt.Sprintf("rId%d", i)
+	}
+	r := relationship{ID: id, Type: relType, Target: target}
+	if external {
+		r.TargetMode = "External"
+	} else if rel, err := relativePath(path.Dir(source), target); err == nil {
+		r.Target = rel
+	}
+	if pc.rels == nil {
+		pc.rels = make(map[string][]relationship)
+	}
+	if _, ok := pc.rels[source]; !ok {
+		pc.sources = append(pc.sources, source)
+	}
+	pc.rels[source] = append(pc.rels[source], r)
+	return id, nil
+}
+
+// Get the path of the target, relative to the base directory. Both are package paths, without leading slash.
+func relativePath(base, target string) (string, error) {
+	if base == "." || base == "" {
+		return target, nil
+	}
+	if strings.HasPrefix(target, base+"/") {
+		return strings.TrimPrefix(target, base+"/"), nil
+	}
+	return "/" + target, nil // absolute part name
+}
+
+// Add a new part to the package, with its content type.
+// If a part with the same name was already added, it is replaced.
+func (pkg *docxPackage) addPart(name, contentType string, data []byte) {
+	pc := &pkg.changes
+	if pc.parts == nil {
+		pc.parts, pc.types = make(map[string][]byte), make(map[string]string)
+	}
+	if _, ok := pc.parts[name]; !ok {
+		pc.names = append(pc.names, name)
+	}
+	pc.parts[name], pc.types[name] = data, contentType
+}
+
+// Check if the package contains a part with this name, including the parts added while rewriting.
+func (pkg *docxPackage) hasPart(name string) bool {
+	if _, ok := pkg.changes.parts[name]; ok {
+		return true
+	}
+	for _, file := range pkg.zr.File {
+		if file.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the xml of the new relationships of a source part.
+func (pc *packageChanges) relsXML(source string) string {
+	var sb strings.Builder
+	for _, r := range pc.rels[source] {
+		fmt.Fprintf(&sb, `<Relationship Id="%s" Type="%s" Target="%s"`, xmlEscape([]byte(r.ID)), xmlEscape([]byte(r.Type)), xmlEscape([]byte(r.Target)))
+		if r.TargetMode != "" {
+			fmt.Fprintf(&sb, ` TargetMode="%s"`, xmlEscape([]byte(r.TargetMode)))
+		}
+		sb.WriteString("/>")
+	}
+	return sb.String()
+}
+
+// Get the updated content of a file that is not a container : relationships with new relationships, content types with new parts,
+// or numbering with new numbering instances.
+// Returns nil if the file is not changed.
+func (pkg *docxPackage) patch(file *zip.File) ([]byte, error) {
+	var addition, element string
+	switch {
+	case file.Name == contentTypesName && len(pkg.changes.names) > 0:
+		var sb strings.Builder
+		for _, name := range pkg.changes.names {
+			fmt.Fprintf(&sb, `<Override PartName="/%s" ContentType="%s"/>`, xmlEscape([]byte(name)), xmlEscape([]byte(pkg.changes.types[name])))
+		}
+		addition, element = sb.String(), "Types"
+	case file.Name == pkg.changes.numbering && len(pkg.changes.nums) > 0:
+		addition, element = strings.Join(pkg.changes.nums, ""), "numbering"
+	case strings.HasSuffix(file.Name, ".rels"):
+		for _, source := range pkg.changes.sources {
+			if relsName(source) == file.Name {
+				addition, element = pkg.changes.relsXML(source), "Relationships"
+			}
+		}
+	}
+	if addition == "" {
+		return nil, nil
+	}
+	content, err := readFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file.Name, err)
+	}
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file.Name, err)
+	}
+	for _, c := range root.children {
+		if c.name.Local == element {
+			if c.end == nil {
+				c.setText("") // expand the self-closing element
+			}
+			if last := c.child("numIdMacAtCleanup"); last != nil {
+				last.insertBefore(&xnode{raw: []byte(addition)}) // the numbering instances precede it
+			} else {
+				c.appendChildren(&xnode{raw: []byte(addition)})
+			}
+		}
+	}
+	return root.bytes(), nil
+}
@@ -0,0 +1,111 @@
+package mydocx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// minimal [Content_Types].xml, with custom part names for header and footnotes
+const testContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/><Override PartName="/word/myHeader.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/><Override PartName="/word/notes.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml"/></Types>`
+
+// namespaces declared on the root element of test containers
+const testNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture" xmlns:wps="http://schemas.microsoft.com/office/word/2010/wordprocessingShape" xmlns:w14="http://schemas.microsoft.com/office/word/2010/wordml" xmlns:v="urn:schemas-microsoft-com:vml"`
+
+// Build a test document.xml from the xml content of its body.
+func testDocument(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<w:document ` + testNamespaces + `><w:body>` + body + `</w:body></w:document>`
+}
+
+// Build a simple paragraph with a single run.
+func testPara(text string) string {
+	return fmt.Sprintf(`<w:p><w:r><w:t xml:space="preserve">%s</w:t></w:r></w:p>`, text)
+}
+
+// Build an in-memory docx from the provided file names and contents.
+func makeDocx(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPartsSelection(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("body")),
+		"word/myHeader.xml": `<w:hdr ` + testNamespaces + `>` + testPara("header") + `</w:hdr>`,
+		"word/notes.xml":    `<w:footnotes ` + testNamespaces + `><w:footnote w:id="1">` + testPara("note") + `</w:footnote></w:footnotes>`,
+	})
+
+	tests := []struct {
+		parts Parts
+		want  []string
+	}{
+		{DefaultParts, []string{"word/document.xml", "word/myHeader.xml"}},
+		{PartHeaders, []string{"word/myHeader.xml"}},
+		{PartBody | PartFootnotes, []string{"word/document.xml", "word/notes.xml"}},
+		{AllParts, []string{"word/document.xml", "word/myHeader.xml", "word/notes.xml"}},
+	}
+
+	for _, tt := range tests {
+		pp, err := ExtractTextBytes(docx, WithParts(tt.parts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pp) != len(tt.want) {
+			t.Errorf("parts %b : got %d containers, want %d : %v", tt.parts, len(pp), len(tt.want), pp)
+		}
+		for _, name := range tt.want {
+			if _, ok := pp[name]; !ok {
+				t.Errorf("parts %b : missing container %s", tt.parts, name)
+			}
+		}
+	}
+
+	// modification only applies to the selected parts
+	out, err := ModifyTextBytes(docx, func(_, s string) []string { return []string{s + "!"} }, WithParts(PartHeaders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out, WithParts(AllParts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp["word/myHeader.xml"][0] != "header!" || pp["word/document.xml"][0] != "body" || pp["word/notes.xml"][0] != "note" {
+		t.Errorf("unexpected modification result : %v", pp)
+	}
+}
+
+func TestModifyFileErrors(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "source.docx")
+	docx := makeDocx(t, map[string]string{contentTypesName: testContentTypes, "word/document.xml": testDocument(testPara("text"))})
+	if err := os.WriteFile(source, docx, 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "missing", "target.docx")
+	if err := ModifyText(source, nil, target); err == nil {
+		t.Error("the write error should be returned")
+	}
+	if err := ModifyText(filepath.Join(t.TempDir(), "missing.docx"), nil, target); err == nil {
+		t.Error("the read error should be returned")
+	}
+}
@@ -0,0 +1,103 @@
+package mydocx
+
+// An Option customizes a single extraction or modification call.
+// Options only apply to the call they are provided to, they never change the package-level settings.
+type Option func(*config)
+
+// config holds the settings of a single call, after all options were applied.
+type config struct {
+	parts      Parts             // selection of the parts to process
+	numbering  bool              // prefix extracted paragraphs with their list label
+	fieldCodes bool              // extract field instructions instead of field results
+	images     map[string][]byte // images replacing the pictures with the same name or description, when modifying
+	errors     bool              // report template errors instead of inserting them in the document
+	failFast   bool              // stop at the first template error
+	markup     bool              // convert the inline markup of the replaced text into formatted runs
+}
+
+// Build the configuration from the provided options, starting from the defaults.
+func newConfig(opts []Option) *config {
+	c := &config{
+		parts: DefaultParts,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	return c
+}
+
+// WithParts selects the parts (containers) that will be processed, based on their content type.
+// By default, the body, the headers and the footers are processed (DefaultParts).
+// Eg : WithParts(PartBody|PartFootnotes) to process only the main body and the footnotes.
+// It applies to every function taking options : extraction, modification, templates, fields, bookmarks, hyperlinks, content controls and images.
+func WithParts(parts Parts) Option {
+	return func(c *config) {
+		c.parts = parts
+	}
+}
+
+// WithNumbering prefixes each extracted paragraph with its list label (eg : "4.2 ", "(b) ", "• "),
+// as computed from the numbering definitions and the paragraph styles of the document.
+// The label is followed by a single space, unless the list level displays nothing after the number.
+// Counters restart for each container. By default, labels are not extracted.
+func WithNumbering() Option {
+	return func(c *config) {
+		c.numbering = true
+	}
+}
+
+// WithFieldCodes extracts the instructions of the fields instead of their results,
+// as Word displays them when showing field codes, eg : "Dear {MERGEFIELD Name}".
+// By default, the current (cached) results of the fields are extracted.
+func WithFieldCodes() Option {
+	return func(c *config) {
+		c.fieldCodes = true
+	}
+}
+
+// WithTemplateErrors reports the template errors as a TemplateErrors error, listing the container, the paragraph and the error of each of them,
+// and no document is produced. By default, each error is inserted in the document, as a paragraph starting with "$$$$$$ ERROR $$$$$",
+// after the paragraph in error, and the call succeeds.
+// It applies to ModifyText with the replacers created by NewTplReplacer, and to ExecuteTemplate.
+func WithTemplateErrors() Option {
+	return func(c *config) {
+		c.errors = true
+	}
+}
+
+// WithFailFast reports the template errors as WithTemplateErrors does, but stops processing at the first error.
+func WithFailFast() Option {
+	return func(c *config) {
+		c.errors, c.failFast = true, true
+	}
+}
+
+// WithMarkup converts the inline markup of the replaced text into formatted runs, when modifying :
+// **bold**, *italic* and __underline__. The new runs inherit the properties of the run of the paragraph.
+// As in markdown, an opening marker is followed by a non space character, and a closing marker follows a non space character.
+// Markers that are not closed within the paragraph are left unchanged, and \* or \_ produce a literal * or _.
+// By default, the replaced text is written as is. The bold, italic and underline template functions do not require this option.
+func WithMarkup() Option {
+	return func(c *config) {
+		c.markup = true
+	}
+}
+
+// Get the report collecting the template errors, or nil if errors are inserted in the document.
+func (c *config) errorReport() *errorReport {
+	if !c.errors {
+		return nil
+	}
+	return &errorReport{failFast: c.failFast}
+}
+
+// WithImages replaces the pictures whose name or description (alt text) matches a key of the map
+// by the provided png, jpeg or gif content, while modifying the text (see ModifyText and ReplaceImages).
+// The size, position and formatting of the pictures are kept.
+func WithImages(images map[string][]byte) Option {
+	return func(c *config) {
+		c.images = images
+	}
+}
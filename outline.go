@@ -0,0 +1,127 @@
+package mydocx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Section is a node of the outline of a document : a heading, the body paragraphs that follow it, and its subsections.
+// The root section of an outline has no heading (level 0) : its paragraphs are the ones found before the first heading.
+type Section struct {
+	// Heading level, from 1 to 9, or 0 for the root of the outline
+	Level int
+	// Numbering label of the heading, if the heading is numbered (eg : "5.3", "IV.")
+	Label string
+	// Text of the heading, without its label
+	Heading string
+	// Body paragraphs of the section, up to the next heading. Paragraphs of the subsections are not included.
+	Paragraphs []string
+	// Subsections, in document order
+	Sections []*Section
+}
+
+// Extract the outline of the main document body : headings (paragraphs with an outline level,
+// or using the built-in Heading 1 ... Heading 9 styles) with their nested body paragraphs.
+// Text is extracted as if all changes were accepted.
+// Heading labels are always computed, the WithNumbering option also prefixes the body paragraphs with their list labels.
+// A heading that skips levels (eg : Heading 3 just after Heading 1) is nested in the previous heading of a lower level.
+// This function is thread-safe.
+func ExtractOutline(sourceFilePath string, opts ...Option) (*Section, error) {
+	return fromFile(sourceFilePath, ExtractOutlineBytes, opts)
+}
+
+// Same as ExtractOutline, but takes a byte array as input.
+func ExtractOutlineBytes(sourceBytes []byte, opts ...Option) (*Section, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	nb, err := pkg.numbering()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load numbering: %v", err)
+	}
+	main := pkg.mainPart()
+	content, err := pkg.read(main)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", main, err)
+	}
+	if content == nil {
+		return nil, fmt.Errorf("no main document found")
+	}
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", main, err)
+	}
+
+	outline := new(Section)
+	stack := []*Section{outline} // current section, and its parents
+	for _, p := range root.paragraphs() {
+		text := paragraphText(p, acceptedView)
+		label, suffix := nb.next(p)
+		level := nb.styles.headingLevel(p)
+		if level == 0 {
+			if conf.numbering && label != "" {
+				text = label + suffix + text
+			}
+			top := stack[len(stack)-1]
+			top.Paragraphs = append(top.Paragraphs, text)
+			continue
+		}
+		for stack[len(stack)-1].Level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		s := &Section{Level: level, Label: label, Heading: text}
+		top := stack[len(stack)-1]
+		top.Sections = append(top.Sections, s)
+		stack = append(stack, s)
+	}
+	return outline, nil
+}
+
+// Find the first section (including the section itself) whose label or heading matches the provided reference.
+// Labels match when equal, ignoring trailing dots and parenthesis (eg : "5.3" matches the label "5.3."),
+// headings match when equal, ignoring case and surrounding spaces.
+// Labels are tried first. Returns nil if not found.
+func (s *Section) Find(ref string) *Section {
+	norm := func(l string) string { return strings.TrimRight(strings.TrimSpace(l), ".)") }
+	if found := s.find(func(c *Section) bool { return c.Label != "" && norm(c.Label) == norm(ref) }); found != nil {
+		return found
+	}
+	return s.find(func(c *Section) bool { return strings.EqualFold(strings.TrimSpace(c.Heading), strings.TrimSpace(ref)) })
+}
+
+// Find the first section matching the predicate, in document order.
+func (s *Section) find(match func(*Section) bool) *Section {
+	if match(s) {
+		return s
+	}
+	for _, c := range s.Sections {
+		if found := c.find(match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Get the paragraphs of the section, including its heading (prefixed with its label) and its subsections, in document order.
+func (s *Section) AllParagraphs() []string {
+	var res []string
+	if s.Level > 0 {
+		h := s.Heading
+		if s.Label != "" {
+			h = s.Label + " " + h
+		}
+		res = append(res, h)
+	}
+	res = append(res, s.Paragraphs...)
+	for _, c := range s.Sections {
+		res = append(res, c.AllParagraphs()...)
+	}
+	return res
+}
+
+// Get the text of the section, including its heading and its subsections. Paragraphs are separated by \n.
+func (s *Section) Text() string {
+	return strings.Join(s.AllParagraphs(), "\n")
+}
@@ -0,0 +1,59 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutline(t *testing.T) {
+
+	heading2 := `<w:pStyle w:val="Heading2"/><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr>`
+	body := testPara("Preamble") +
+		testStyledPara(`<w:pStyle w:val="Heading1"/>`, "Introduction") +
+		testPara("Intro text") +
+		testStyledPara(heading2, "Scope") +
+		testPara("Scope text") +
+		testListPara(1, 2, "Item") +
+		testStyledPara(heading2, "Terms") +
+		testPara("Terms text") +
+		testStyledPara(`<w:pStyle w:val="Heading1"/>`, "Obligations") +
+		testStyledPara(`<w:outlineLvl w:val="1"/>`, "Direct outline") +
+		testPara("End")
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:     testContentTypes,
+		"word/document.xml":  testDocument(body),
+		"word/numbering.xml": testNumbering,
+		"word/styles.xml":    testStyles,
+	})
+
+	outline, err := ExtractOutlineBytes(docx, WithNumbering())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outline.Level != 0 || len(outline.Sections) != 2 || strings.Join(outline.Paragraphs, "|") != "Preamble" {
+		t.Fatalf("unexpected outline root : %+v", outline)
+	}
+	intro := outline.Find("introduction")
+	if intro == nil || intro.Label != "I." || intro.Level != 1 || len(intro.Sections) != 2 {
+		t.Fatalf("unexpected introduction section : %+v", intro)
+	}
+	scope := outline.Find("1.1")
+	if scope == nil || scope.Heading != "Scope" || strings.Join(scope.Paragraphs, "|") != "Scope text|(a) Item" {
+		t.Errorf("unexpected scope section : %+v", scope)
+	}
+	if s := outline.Find("1.2"); s == nil || s.Text() != "1.2 Terms\nTerms text" {
+		t.Errorf("unexpected terms section : %+v", s)
+	}
+	obligations := outline.Find("II")
+	if obligations == nil || len(obligations.Sections) != 1 || obligations.Sections[0].Heading != "Direct outline" ||
+		obligations.Sections[0].Level != 2 || strings.Join(obligations.Sections[0].Paragraphs, "|") != "End" {
+		t.Errorf("unexpected obligations section : %+v", obligations)
+	}
+	if outline.Find("9.9") != nil {
+		t.Errorf("unexpected section found")
+	}
+	if got := len(outline.AllParagraphs()); got != 11 {
+		t.Errorf("got %d paragraphs in the whole outline, want 11", got)
+	}
+}
@@ -0,0 +1,99 @@
+package mydocx
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"text/template"
+)
+
+// A Processor holds the settings used to modify documents, and the functions available to their templates.
+// The package-level functions (ModifyText, NewTplReplacer, ExecuteTemplate ...) use the package-level settings
+// (VERBOSE, REMOVE_EMPTY_PARAGRAPH) and the functions registered with RegisterTplFunction.
+// A Processor is independent of them, and of the other processors : it can be used to process documents concurrently,
+// with different settings and functions, as long as it is not changed while in use.
+type Processor struct {
+	// Print verbose information to stdout.
+	Verbose bool
+	// Remove the paragraphs that become empty after replacement (paragraphs that were initially empty are never removed).
+	// The template functions {{removeEmpty}} and {{keepEmpty}} change this setting for the rest of the document being processed only.
+	RemoveEmptyParagraph bool
+	// Print detailed debugging information.
+	Debug bool
+	// Delimiters of the template actions, {{ and }} if empty (eg : [[ and ]], for documents holding {{ in their text).
+	// A {{delims "[[" "]]"}} directive, written with the current delimiters, changes them for the rest of the document.
+	LeftDelim, RightDelim string
+
+	funcs   template.FuncMap // functions available to the templates
+	reading string           // state of the template reader, prefixing the next paragraph of the document (see NewTplReplacer)
+}
+
+// Create a new Processor, removing empty paragraphs, with the built-in template functions
+// and the functions registered so far with RegisterTplFunction.
+func NewProcessor() *Processor {
+	return &Processor{RemoveEmptyParagraph: true, funcs: maps.Clone(functionMap)}
+}
+
+// Get the processor used by the package-level functions, from the package-level settings.
+func defaultProcessor() *Processor {
+	return &Processor{Verbose: VERBOSE, RemoveEmptyParagraph: REMOVE_EMPTY_PARAGRAPH, Debug: debugflag, funcs: functionMap}
+}
+
+// Register a new function that will be available when parsing the templates of this Processor only.
+// See RegisterTplFunction.
+func (p *Processor) RegisterTplFunction(name string, function any) {
+	if p.funcs == nil {
+		p.funcs = make(template.FuncMap)
+	}
+	if name != "" && function != nil {
+		if p.Verbose {
+			fmt.Printf("Registering template function {{%s}} : %T\n", name, function)
+		}
+		p.funcs[name] = function
+	}
+}
+
+// Get a copy of the processor, whose settings may change while processing a single document.
+func (p *Processor) session() *Processor {
+	s := *p
+	return &s
+}
+
+// Setting changed from a template, as an inline object in the replaced text (eg : {{keepEmpty}}).
+var settingPattern = regexp.MustCompile(regexp.QuoteMeta(inlineStart+"setting"+inlineSep) + `(\w+)` + regexp.QuoteMeta(inlineEnd))
+
+// Get the markup changing a setting of the processor, for the rest of the document.
+func setting(name string) string {
+	return inlineObject("setting", name)
+}
+
+// Apply the settings found in the replaced text, in order, and remove them from the text (see removeSettings).
+func (p *Processor) applySettings(paras []string) []string {
+	for _, s := range paras {
+		for _, m := range settingPattern.FindAllStringSubmatch(s, -1) {
+			switch m[1] {
+			case "removeEmpty":
+				p.RemoveEmptyParagraph = true
+			case "keepEmpty":
+				p.RemoveEmptyParagraph = false
+			}
+		}
+	}
+	return removeSettings(paras)
+}
+
+// Remove the settings from the replaced text, in place.
+// A single paragraph holding only settings is dropped, as an empty template result.
+func removeSettings(paras []string) []string {
+	found := false
+	for i, s := range paras {
+		if settingPattern.MatchString(s) {
+			found = true
+			paras[i] = settingPattern.ReplaceAllString(s, "")
+		}
+	}
+	if found && len(paras) == 1 && paras[0] == "" {
+		return nil
+	}
+	return paras
+}
@@ -0,0 +1,63 @@
+package mydocx
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProcessor(t *testing.T) {
+
+	keep := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("{{keepEmpty}}{{shout .Name}}") + testPara("{{.Empty}}") + testPara("end")),
+	})
+	remove := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("{{shout .Name}}") + testPara("{{.Empty}}") + testPara("end")),
+	})
+	data := map[string]string{"Name": "john", "Empty": ""}
+
+	p := NewProcessor()
+	p.RegisterTplFunction("shout", strings.ToUpper)
+	check := func(docx []byte, want string) {
+		out, err := p.ModifyTextBytes(docx, p.NewTplReplacer(data))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		pp, err := ExtractTextBytes(out)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if got := strings.Join(pp["word/document.xml"], "|"); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	// {{keepEmpty}} only applies to its own document, even when documents are processed concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); check(keep, "JOHN||end") }()
+		go func() { defer wg.Done(); check(remove, "JOHN|end") }()
+	}
+	wg.Wait()
+	if !p.RemoveEmptyParagraph || !REMOVE_EMPTY_PARAGRAPH {
+		t.Errorf("settings should not be changed by the templates")
+	}
+
+	// functions registered on a processor are not available to the package-level functions
+	out, err := ExecuteTemplateBytes(remove, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) < 2 || !strings.Contains(got[1], `function "shout" not defined`) {
+		t.Errorf("unexpected text : %q", got)
+	}
+}
@@ -0,0 +1,312 @@
+package mydocx
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TemplateField is a field of the data referenced by the templates of a document, with the fields referenced within it.
+type TemplateField struct {
+	// Name of the field, as referenced by the templates (eg : Name)
+	Name string
+	// Path of the field from the data (eg : .Customer.Name). Fields of the elements of a list are noted .Items[].Qty
+	Path string
+	// The field is ranged over : it is a slice, an array or a map, and Fields are the fields of its elements
+	List bool
+	// The field is only used as a condition, by if actions
+	Condition bool
+	// Fields referenced within the field, or within its elements, in order of first reference
+	Fields []*TemplateField
+
+	printed bool // the field is used otherwise than as a condition
+}
+
+// Get the field with the provided name, creating it if needed.
+func (f *TemplateField) field(name string) *TemplateField {
+	for _, c := range f.Fields {
+		if c.Name == name {
+			return c
+		}
+	}
+	path := f.Path
+	if f.List {
+		path += "[]"
+	}
+	c := &TemplateField{Name: name, Path: path + "." + name}
+	f.Fields = append(f.Fields, c)
+	return c
+}
+
+// Mark the field as ranged over, updating the paths of its fields.
+func (f *TemplateField) list() {
+	if f.List {
+		return
+	}
+	f.List = true
+	var rebase func(c *TemplateField)
+	rebase = func(c *TemplateField) {
+		for _, cc := range c.Fields {
+			cc.Path = c.Path
+			if c.List {
+				cc.Path += "[]"
+			}
+			cc.Path += "." + cc.Name
+			rebase(cc)
+		}
+	}
+	rebase(f)
+}
+
+// Extract the fields of the data referenced by the templates of the docx file, as ExecuteTemplate executes them,
+// with the fields referenced within them. Fields are followed through range, with and template actions, and variables.
+// The parts of the templates that cannot be parsed are ignored : the fields of the other parts are returned,
+// with the problems found as a TemplateErrors error (see ValidateTemplateBlocks).
+// Use TemplateSchema or TemplateStruct to describe the expected data.
+func ExtractTemplateFields(sourceFilePath string, opts ...Option) ([]*TemplateField, error) {
+	return fromFile(sourceFilePath, ExtractTemplateFieldsBytes, opts)
+}
+
+// Same as ExtractTemplateFields, but takes a byte array as input.
+func ExtractTemplateFieldsBytes(sourceBytes []byte, opts ...Option) ([]*TemplateField, error) {
+	return defaultProcessor().ExtractTemplateFieldsBytes(sourceBytes, opts...)
+}
+
+// Same as ExtractTemplateFieldsBytes, with the template functions of the Processor.
+func (p *Processor) ExtractTemplateFieldsBytes(sourceBytes []byte, opts ...Option) ([]*TemplateField, error) {
+	v, err := p.parseDocument(sourceBytes, opts)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := v.parseBlocks(p.funcs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the templates: %v", err)
+	}
+	root := &TemplateField{}
+	fc := &fieldCollector{tpl: tpl, visited: make(map[string]bool)}
+	fc.node(tpl.Tree.Root, root, map[string]*TemplateField{"$": root})
+	var finish func(f *TemplateField)
+	finish = func(f *TemplateField) {
+		f.Condition = f.Condition && !f.printed && !f.List && len(f.Fields) == 0
+		for _, c := range f.Fields {
+			finish(c)
+		}
+	}
+	finish(root)
+	if len(v.errs) > 0 {
+		return root.Fields, v.sorted()
+	}
+	return root.Fields, nil
+}
+
+// fieldCollector follows the field referenced by dot and by the variables through a parse tree, and records the fields referenced from them.
+// A nil field is unknown, such as the result of a function : its fields are not recorded.
+type fieldCollector struct {
+	tpl     *template.Template
+	visited map[string]bool // templates already followed, by name and path of dot
+}
+
+// Collect the fields of a node, with the fields of dot and of the visible variables.
+func (fc *fieldCollector) node(n parse.Node, dot *TemplateField, vars map[string]*TemplateField) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			fc.node(child, dot, vars)
+		}
+	case *parse.ActionNode:
+		fc.declare(n.Pipe, fc.pipe(n.Pipe, dot, vars, false), vars)
+	case *parse.IfNode:
+		inner := copyFields(vars)
+		fc.declare(n.Pipe, fc.pipe(n.Pipe, dot, inner, true), inner)
+		fc.node(n.List, dot, inner)
+		fc.node(n.ElseList, dot, copyFields(vars))
+	case *parse.WithNode:
+		inner := copyFields(vars)
+		f := fc.pipe(n.Pipe, dot, inner, true)
+		fc.declare(n.Pipe, f, inner)
+		fc.node(n.List, f, inner)
+		fc.node(n.ElseList, dot, copyFields(vars))
+	case *parse.RangeNode:
+		inner := copyFields(vars)
+		f := fc.pipe(n.Pipe, dot, inner, false)
+		if f != nil {
+			f.list()
+		}
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner[n.Pipe.Decl[0].Ident[0]] = f
+		case 2:
+			inner[n.Pipe.Decl[0].Ident[0]], inner[n.Pipe.Decl[1].Ident[0]] = nil, f
+		}
+		fc.node(n.List, f, inner)
+		fc.node(n.ElseList, dot, copyFields(vars))
+	case *parse.TemplateNode:
+		var f *TemplateField
+		if n.Pipe != nil {
+			f = fc.pipe(n.Pipe, dot, vars, false)
+		}
+		def := fc.tpl.Lookup(n.Name)
+		if def == nil || def.Tree == nil || f == nil {
+			return
+		}
+		key := n.Name + " " + f.Path
+		if fc.visited[key] {
+			return
+		}
+		fc.visited[key] = true
+		fc.node(def.Tree.Root, f, map[string]*TemplateField{"$": f})
+	}
+}
+
+// Record the field of the variable declared by a pipeline, if any.
+func (fc *fieldCollector) declare(pipe *parse.PipeNode, f *TemplateField, vars map[string]*TemplateField) {
+	if pipe != nil && len(pipe.Decl) == 1 && !pipe.IsAssign {
+		vars[pipe.Decl[0].Ident[0]] = f
+	}
+}
+
+// Collect the fields of the arguments of a pipeline, and get the field of its value.
+// A pipeline that is a condition does not print its value.
+func (fc *fieldCollector) pipe(pipe *parse.PipeNode, dot *TemplateField, vars map[string]*TemplateField, condition bool) *TemplateField {
+	if pipe == nil {
+		return nil
+	}
+	var res *TemplateField
+	for _, cmd := range pipe.Cmds {
+		res = nil
+		for i, arg := range cmd.Args {
+			value := i == 0 && len(cmd.Args) == 1 && len(pipe.Cmds) == 1
+			f := fc.arg(arg, dot, vars)
+			if f == nil {
+				continue
+			}
+			if value && condition {
+				f.Condition = true
+			} else {
+				f.printed = true
+			}
+			if _, field := arg.(*parse.FieldNode); i == 0 && (len(cmd.Args) == 1 || field) {
+				res = f
+			}
+		}
+	}
+	return res
+}
+
+// Collect the fields of an argument, and get its field.
+func (fc *fieldCollector) arg(n parse.Node, dot *TemplateField, vars map[string]*TemplateField) *TemplateField {
+	switch n := n.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return fields(dot, n.Ident)
+	case *parse.VariableNode:
+		return fields(vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		return fields(fc.arg(n.Node, dot, vars), n.Field)
+	case *parse.PipeNode:
+		return fc.pipe(n, dot, vars, false)
+	}
+	return nil
+}
+
+// Follow a chain of field names from a field, recording them, and get the last one.
+func fields(f *TemplateField, names []string) *TemplateField {
+	for _, name := range names {
+		if f == nil {
+			return nil
+		}
+		f = f.field(name)
+	}
+	return f
+}
+
+// Copy the fields of the variables, for a nested scope.
+func copyFields(vars map[string]*TemplateField) map[string]*TemplateField {
+	res := make(map[string]*TemplateField, len(vars))
+	for k, f := range vars {
+		res[k] = f
+	}
+	return res
+}
+
+// jsonSchema is the subset of JSON Schema describing the data of templates.
+type jsonSchema struct {
+	Schema     string                 `json:"$schema,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Properties map[string]*jsonSchema `json:"properties,omitempty"`
+	Items      *jsonSchema            `json:"items,omitempty"`
+}
+
+// Get the JSON Schema of the data expected by templates, from their fields (see ExtractTemplateFields).
+// Fields with fields are objects, lists are arrays, fields only used as conditions are booleans, and other fields are strings.
+func TemplateSchema(fields []*TemplateField) ([]byte, error) {
+	s := schemaOf(&TemplateField{Fields: fields})
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// Get the schema of a field, or of its elements for a list.
+func schemaOf(f *TemplateField) *jsonSchema {
+	s := &jsonSchema{Type: "string"}
+	switch {
+	case len(f.Fields) > 0:
+		s = &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema)}
+		for _, c := range f.Fields {
+			s.Properties[c.Name] = schemaOf(c)
+		}
+	case f.Condition:
+		s.Type = "boolean"
+	}
+	if f.List {
+		return &jsonSchema{Type: "array", Items: s}
+	}
+	return s
+}
+
+// Get the declaration of a go struct type, named name, providing the data expected by templates, from their fields (see ExtractTemplateFields).
+// Fields with fields are nested structs, lists are slices, fields only used as conditions are booleans, and other fields are strings.
+// Fields that a struct cannot provide, because their name is not exported, are commented : use a map instead.
+func TemplateStruct(fields []*TemplateField, name string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "type %s ", name)
+	writeStruct(&sb, fields)
+	res, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
+
+// Write the struct type providing the fields.
+func writeStruct(sb *strings.Builder, fields []*TemplateField) {
+	sb.WriteString("struct {\n")
+	for _, f := range fields {
+		if r, _ := utf8.DecodeRuneInString(f.Name); !unicode.IsUpper(r) {
+			fmt.Fprintf(sb, "// %s : not exported, use a map\n", f.Path)
+			continue
+		}
+		sb.WriteString(f.Name + " ")
+		if f.List {
+			sb.WriteString("[]")
+		}
+		switch {
+		case len(f.Fields) > 0:
+			writeStruct(sb, f.Fields)
+		case f.Condition:
+			sb.WriteString("bool")
+		default:
+			sb.WriteString("string")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+}
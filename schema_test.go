@@ -0,0 +1,68 @@
+package mydocx
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFields(t *testing.T) {
+
+	body := testPara("Dear {{.Customer.Name}}, {{printf \"%s\" .Customer.City}}") +
+		testPara("{{if .Premium}}Premium{{end}}{{with .Note}}{{.}}{{end}}") +
+		testPara("{{range $i, $item := .Items}}{{$item.Name}} {{.Qty}}{{range .Options}}{{.Label}}{{end}}{{end}}") +
+		testPara("{{$c := .Customer}}{{$c.Phone}}{{define \"addr\"}}{{.Street}}{{end}}{{template \"addr\" .Customer.Address}}") +
+		testPara("{{.Tags.vip}} {{len .Lines}} {{.Broken") // parts in error are ignored
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+
+	fields, err := ExtractTemplateFieldsBytes(docx)
+	var te TemplateErrors
+	if !errors.As(err, &te) || len(te) != 1 || te[0].Paragraph != 4 {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	var paths []string
+	var walk func(ff []*TemplateField)
+	walk = func(ff []*TemplateField) {
+		for _, f := range ff {
+			paths = append(paths, f.Path)
+			walk(f.Fields)
+		}
+	}
+	walk(fields)
+	want := ".Customer .Customer.Name .Customer.City .Customer.Phone .Customer.Address .Customer.Address.Street .Premium .Note " +
+		".Items .Items[].Name .Items[].Qty .Items[].Options .Items[].Options[].Label .Tags .Tags.vip .Lines"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	schema, err := TemplateSchema(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s map[string]any
+	if err := json.Unmarshal(schema, &s); err != nil {
+		t.Fatal(err)
+	}
+	props := s["properties"].(map[string]any)
+	if props["Premium"].(map[string]any)["type"] != "boolean" || props["Note"].(map[string]any)["type"] != "string" ||
+		props["Items"].(map[string]any)["type"] != "array" {
+		t.Errorf("unexpected schema :\n%s", schema)
+	}
+	if !strings.Contains(string(schema), `"Label": {`) {
+		t.Errorf("unexpected schema :\n%s", schema)
+	}
+
+	code, err := TemplateStruct(fields, "Invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{"type Invoice struct {", "\tPremium bool\n", "\tItems   []struct {\n", "\t\tOptions []struct {\n\t\t\tLabel string\n", "// .Tags.vip : not exported, use a map"} {
+		if !strings.Contains(code, w) {
+			t.Errorf("missing %q in\n%s", w, code)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package mydocx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Namespace of the Word 2010 extensions, used by check box content controls.
+const w14Namespace = "http://schemas.microsoft.com/office/word/2010/wordml"
+
+// Types of content controls, as reported in ContentControl.Type.
+// Other kinds of content controls (group, building block gallery, citation, ...) are reported with the name of their xml element.
+const (
+	ControlRichText = "richText"
+	ControlText     = "text"
+	ControlDate     = "date"
+	ControlDropDown = "dropDownList"
+	ControlComboBox = "comboBox"
+	ControlCheckBox = "checkBox"
+	ControlPicture  = "picture"
+)
+
+// ContentControl describes a content control (structured document tag, w:sdt) of a document.
+type ContentControl struct {
+	// Name of the container where the content control was found (eg : word/document.xml)
+	Container string
+	// Tag of the content control, as set in the developer mode of Word
+	Tag string
+	// Alias (title) of the content control
+	Alias string
+	// Type of the content control : ControlRichText, ControlText, ControlDate, ...
+	Type string
+	// Current value. Paragraphs are separated by \n. Check boxes have the value "true" or "false".
+	Value string
+	// Display texts of the items of drop down lists and combo boxes.
+	Items []string
+	// True if the content control currently shows its placeholder text
+	Placeholder bool
+}
+
+// List the content controls of the docx file, in reading order of the containers, then document order.
+// Nested content controls are listed after their parent.
+func ListContentControls(sourceFilePath string, opts ...Option) ([]ContentControl, error) {
+	return fromFile(sourceFilePath, ListContentControlsBytes, opts)
+}
+
+// Same as ListContentControls, but takes a byte array as input.
+func ListContentControlsBytes(sourceBytes []byte, opts ...Option) ([]ContentControl, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	containers, err := pkg.containers(conf.parts)
+	if err != nil {
+		return nil, err
+	}
+	var res []ContentControl
+	for _, c := range containers {
+		content, err := pkg.read(c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", c.Name, err)
+		}
+		root, err := parseTree(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", c.Name, err)
+		}
+		for _, sdt := range root.collect("sdt") {
+			cc := describeControl(sdt)
+			cc.Container = c.Name
+			res = append(res, cc)
+		}
+	}
+	return res, nil
+}
+
+// Fill the content controls of the docx file with the provided data, matching the tag (or else the alias) of each control.
+// Data can be a map with string keys, or a struct (fields are matched by their `docx:"tag"` struct tag, or by their name, ignoring case).
+// Content controls with no matching value are left unchanged.
+// Check boxes expect a boolean, dates expect a time.Time (or a string such as 2006-01-02) and are formatted with the format of the control.
+// Drop down lists and combo boxes display the text of the item whose value or text matches the provided value.
+// Data bindings to custom xml are removed from the filled controls, so that Word keeps the new values.
+// If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
+func FillContentControls(sourceFilePath string, data any, targetFilePath string, opts ...Option) error {
+	return modifyFile(sourceFilePath, targetFilePath, "fill content controls", func(in []byte) ([]byte, error) {
+		return FillContentControlsBytes(in, data, opts...)
+	})
+}
+
+// Same as FillContentControls, but takes a byte array as input and returns the modified docx as a byte array.
+func FillContentControlsBytes(sourceBytes []byte, data any, opts ...Option) ([]byte, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input bytes: %v", err)
+	}
+	ss, err := pkg.styleSheet()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load styles: %v", err)
+	}
+	return pkg.rewrite(conf.parts, func(_ string, root *xnode) error {
+		root.walk(func(n *xnode) bool {
+			if !n.is("sdt") {
+				return true
+			}
+			cc := describeControl(n)
+			value, ok := lookupValue(data, cc.Tag)
+			if !ok && cc.Alias != "" {
+				value, ok = lookupValue(data, cc.Alias)
+			}
+			if !ok {
+				return true // look for nested controls
+			}
+			if VERBOSE {
+				fmt.Printf("Filling content control %q with %v\n", cc.Tag, value)
+			}
+			return !fillControl(n, cc, value, ss.placeholder) // filled controls are not visited further
+		})
+		return nil
+	})
+}
+
+// Describe a content control from its w:sdt element. The container is not set.
+func describeControl(sdt *xnode) (cc ContentControl) {
+	cc.Type = ControlRichText
+	pr, content := sdt.child("sdtPr"), sdt.child("sdtContent")
+	if pr != nil {
+		for _, c := range pr.children {
+			switch {
+			case c.is("tag"):
+				cc.Tag = c.val()
+			case c.is("alias"):
+				cc.Alias = c.val()
+			case c.is("showingPlcHdr"):
+				cc.Placeholder = c.val() != "0" && c.val() != "false"
+			case c.isNS(w14Namespace, "checkbox"):
+				cc.Type = ControlCheckBox
+			case c.is("text"), c.is("date"), c.is("dropDownList"), c.is("comboBox"), c.is("picture"),
+				c.is("group"), c.is("docPartObj"), c.is("docPartList"), c.is("citation"), c.is("bibliography"), c.is("equation"):
+				cc.Type = c.name.Local
+				for _, item := range c.findAll("listItem") {
+					cc.Items = append(cc.Items, itemText(item))
+				}
+			}
+		}
+	}
+	if cc.Type == ControlCheckBox {
+		cc.Value = strconv.FormatBool(checkBoxState(pr))
+	} else if content != nil {
+		cc.Value = contentText(content)
+	}
+	return cc
+}
+
+// Get the display text of a list item, defaulting to its value.
+func itemText(item *xnode) string {
+	if t, ok := item.attrValue(NAMESPACE, "displayText"); ok {
+		return t
+	}
+	v, _ := item.attrValue(NAMESPACE, "value")
+	return v
+}
+
+// Get the checked state of a check box content control, from its properties.
+func checkBoxState(pr *xnode) bool {
+	for _, cb := range pr.children {
+		if cb.isNS(w14Namespace, "checkbox") {
+			for _, c := range cb.children {
+				if c.isNS(w14Namespace, "checked") {
+					v, _ := c.attrValue(w14Namespace, "val")
+					return v == "1" || v == "true"
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Get the text of a content control content, as if all changes were accepted.
+// Block level content is made of paragraphs, separated by \n.
+func contentText(content *xnode) string {
+	paras := content.paragraphs()
+	if len(paras) == 0 {
+		return paragraphText(content, acceptedView)
+	}
+	texts := make([]string, len(paras))
+	for i, p := range paras {
+		texts[i] = paragraphText(p, acceptedView)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Fill a content control with the provided value, according to its type.
+// The placeholder character style (see styleSheet) is removed from the content showing the placeholder, other character styles are kept.
+// Returns false if the content control type cannot be filled.
+func fillControl(sdt *xnode, cc ContentControl, value any, placeholder string) bool {
+	pr, content := sdt.child("sdtPr"), sdt.child("sdtContent")
+	if pr == nil || content == nil {
+		return false
+	}
+	text := toText(value)
+	switch cc.Type {
+	case ControlCheckBox:
+		checked := toBool(value)
+		symbol := map[bool]string{true: "2612", false: "2610"} // default ballot box symbols
+		for _, cb := range pr.children {
+			if !cb.isNS(w14Namespace, "checkbox") {
+				continue
+			}
+			for _, c := range cb.children {
+				switch {
+				case c.isNS(w14Namespace, "checked"):
+					c.setAttr("w14:val", map[bool]string{true: "1", false: "0"}[checked])
+				case c.isNS(w14Namespace, "checkedState"):
+					symbol[true], _ = c.attrValue(w14Namespace, "val")
+				case c.isNS(w14Namespace, "uncheckedState"):
+					symbol[false], _ = c.attrValue(w14Namespace, "val")
+				}
+			}
+		}
+		code, _ := strconv.ParseInt(symbol[checked], 16, 32)
+		text = string(rune(code))
+	case ControlDate:
+		if t, ok := toDate(value); ok {
+			date := pr.child("date")
+			picture := "M/d/yyyy"
+			if df := date.child("dateFormat"); df != nil {
+				picture = df.val()
+			}
+			text = formatWordDate(t, picture)
+			date.setAttr("w:fullDate", t.Format("2006-01-02T15:04:05Z"))
+		}
+	case ControlDropDown, ControlComboBox:
+		for _, item := range pr.findAll("listItem") {
+			if v, _ := item.attrValue(NAMESPACE, "value"); v == text || itemText(item) == text {
+				text = itemText(item)
+				break
+			}
+		}
+	case ControlPicture, "group", "docPartObj", "docPartList", "citation", "bibliography", "equation":
+		return false
+	}
+
+	// the placeholder text style no longer applies, and the data binding would restore the previous value
+	for _, c := range append([]*xnode(nil), pr.children...) {
+		if c.is("showingPlcHdr") || c.is("dataBinding") {
+			c.remove()
+			if cc.Placeholder {
+				for _, rs := range content.findAll("rStyle") {
+					if rs.val() == placeholder {
+						rs.remove()
+					}
+				}
+			}
+		}
+	}
+	setContentText(content, pr.child("rPr"), text)
+	return true
+}
+
+// Replace the text of a content control content.
+// Block level content keeps its first paragraph for the first line, duplicated for the following lines.
+// Run level content keeps its first run, lines are separated by breaks.
+// If there is no run to hold the text, a new run is created with the provided run properties (that may be nil).
+func setContentText(content *xnode, rPr *xnode, text string) {
+	var paras []*xnode
+	for _, c := range content.children {
+		if c.is("p") {
+			paras = append(paras, c)
+		}
+	}
+	if len(paras) == 0 {
+		setRunsText(content, rPr, text)
+		return
+	}
+	for _, p := range paras[1:] {
+		p.remove()
+	}
+	lines := strings.Split(text, "\n")
+	setRunsText(paras[0], rPr, lines[0])
+	prev := paras[0]
+	for _, line := range lines[1:] {
+		dup := paras[0].clone()
+		setRunsText(dup, rPr, line)
+		prev.insertAfter(dup)
+		prev = dup
+	}
+}
+
+// Save the text in the first text element found in n, empty the others.
+// Lines are separated by breaks within the same run.
+// If n has no text element, a new run is appended, with the provided run properties (that may be nil).
+func setRunsText(n *xnode, rPr *xnode, text string) {
+	texts := paragraphTexts(n, acceptedView)
+	if len(texts) == 0 {
+		props := ""
+		if rPr != nil {
+			props = string(rPr.bytes())
+		}
+		run, err := parseFragment(`<w:r>` + props + `<w:t xml:space="preserve"></w:t></w:r>`)
+		if err != nil {
+			panic("invalid run fragment : " + err.Error()) // should never happen
+		}
+		n.appendChildren(run...)
+		texts = paragraphTexts(n, acceptedView)
+	}
+	for _, t := range texts[1:] {
+		t.setText("")
+	}
+	setTextLines(texts[0], text)
+}
+
+// Set the text of a text element. Lines are separated by breaks, inserted as siblings within the same run.
+func setTextLines(t *xnode, text string) {
+	lines := strings.Split(text, "\n")
+	t.setText(lines[0])
+	if strings.TrimSpace(lines[0]) != lines[0] {
+		t.setAttr("xml:space", "preserve")
+	}
+	prev := t
+	for _, line := range lines[1:] {
+		nodes, err := parseFragment(`<w:br/><w:t xml:space="preserve">` + string(xmlEscape([]byte(line))) + `</w:t>`)
+		if err != nil {
+			panic("invalid break fragment : " + err.Error()) // should never happen
+		}
+		prev.insertAfter(nodes...)
+		prev = nodes[len(nodes)-1]
+	}
+}
+
+// Convert a value into a boolean.
+// Strings such as "true", "yes", "x" or "1" are true, as are non zero numbers.
+func toBool(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case nil:
+		return false
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "y", "x", "1", "on", "checked":
+			return true
+		}
+		return false
+	}
+	f, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+	return err == nil && f != 0
+}
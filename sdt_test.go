@@ -0,0 +1,126 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// content controls : run level plain text with placeholder, block level rich text, check box, date and drop down list
+const testControls = `<w:p><w:r><w:t xml:space="preserve">Name : </w:t></w:r><w:sdt><w:sdtPr><w:rPr><w:b/></w:rPr><w:alias w:val="Customer name"/><w:tag w:val="name"/><w:showingPlcHdr/><w:text/></w:sdtPr><w:sdtContent><w:r><w:rPr><w:rStyle w:val="PlaceholderText"/></w:rPr><w:t>Click here</w:t></w:r></w:sdtContent></w:sdt></w:p>` +
+	`<w:sdt><w:sdtPr><w:tag w:val="notes"/></w:sdtPr><w:sdtContent><w:p><w:r><w:t>Old first</w:t></w:r></w:p><w:p><w:r><w:t>Old second</w:t></w:r></w:p></w:sdtContent></w:sdt>` +
+	`<w:p><w:sdt><w:sdtPr><w:tag w:val="agree"/><w14:checkbox><w14:checked w14:val="0"/><w14:checkedState w14:val="2612"/><w14:uncheckedState w14:val="2610"/></w14:checkbox></w:sdtPr><w:sdtContent><w:r><w:t>☐</w:t></w:r></w:sdtContent></w:sdt></w:p>` +
+	`<w:p><w:sdt><w:sdtPr><w:tag w:val="signed"/><w:date><w:dateFormat w:val="dd/MM/yyyy"/></w:date></w:sdtPr><w:sdtContent><w:r><w:t>date</w:t></w:r></w:sdtContent></w:sdt></w:p>` +
+	`<w:p><w:sdt><w:sdtPr><w:tag w:val="size"/><w:dropDownList><w:listItem w:displayText="Small" w:value="S"/><w:listItem w:displayText="Large" w:value="L"/></w:dropDownList></w:sdtPr><w:sdtContent><w:r><w:t>Small</w:t></w:r></w:sdtContent></w:sdt></w:p>`
+
+func TestContentControls(t *testing.T) {
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testControls),
+	})
+
+	cc, err := ListContentControlsBytes(docx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ContentControl{
+		{Tag: "name", Alias: "Customer name", Type: ControlText, Value: "Click here", Placeholder: true},
+		{Tag: "notes", Type: ControlRichText, Value: "Old first\nOld second"},
+		{Tag: "agree", Type: ControlCheckBox, Value: "false"},
+		{Tag: "signed", Type: ControlDate, Value: "date"},
+		{Tag: "size", Type: ControlDropDown, Value: "Small", Items: []string{"Small", "Large"}},
+	}
+	if len(cc) != len(want) {
+		t.Fatalf("got %d content controls, want %d : %+v", len(cc), len(want), cc)
+	}
+	for i, c := range cc {
+		w := want[i]
+		if c.Tag != w.Tag || c.Alias != w.Alias || c.Type != w.Type || c.Value != w.Value || c.Placeholder != w.Placeholder ||
+			strings.Join(c.Items, "|") != strings.Join(w.Items, "|") || c.Container != "word/document.xml" {
+			t.Errorf("control %d : got %+v, want %+v", i, c, w)
+		}
+	}
+
+	data := struct {
+		Name   string `docx:"name"`
+		Notes  string
+		Agree  bool
+		Signed time.Time
+		Size   string
+	}{"John Doe", "New first\nNew second\nNew third", true, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), "L"}
+
+	out, err := FillContentControlsBytes(docx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err = ListContentControlsBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []string{"John Doe", "New first\nNew second\nNew third", "true", "14/03/2025", "Large"}
+	for i, c := range cc {
+		if c.Value != values[i] {
+			t.Errorf("control %s : got %q, want %q", c.Tag, c.Value, values[i])
+		}
+		if c.Placeholder {
+			t.Errorf("control %s still shows its placeholder", c.Tag)
+		}
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp["word/document.xml"][0] != "Name : John Doe" || pp["word/document.xml"][4] != "☒" {
+		t.Errorf("unexpected text after filling : %q", pp["word/document.xml"])
+	}
+
+	// maps are accepted too, unknown tags are left unchanged
+	out, err = FillContentControlsBytes(docx, map[string]any{"Customer name": "Jane"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc, err = ListContentControlsBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc[0].Value != "Jane" || cc[1].Value != "Old first\nOld second" {
+		t.Errorf("unexpected values after filling from map : %+v", cc)
+	}
+}
+
+func TestFillControlDetails(t *testing.T) {
+
+	// the placeholder style is removed, the character styles chosen by the author are kept
+	body := `<w:p><w:sdt><w:sdtPr><w:tag w:val="name"/><w:showingPlcHdr/><w:text/></w:sdtPr><w:sdtContent><w:r><w:rPr><w:rStyle w:val="Strong"/></w:rPr><w:t>Name</w:t></w:r><w:r><w:rPr><w:rStyle w:val="PlaceholderText"/></w:rPr><w:t> here</w:t></w:r></w:sdtContent></w:sdt></w:p>` +
+		`<w:p><w:sdt><w:sdtPr><w:tag w:val="city"/></w:sdtPr><w:sdtContent><w:r><w:t>city</w:t></w:r></w:sdtContent></w:sdt></w:p>` +
+		`<w:p><w:sdt><w:sdtPr><w:tag w:val="signed"/><w:date><w:dateFormat w:val="'Mon' d MMM 'of' yyyy"/></w:date></w:sdtPr><w:sdtContent><w:r><w:t>date</w:t></w:r></w:sdtContent></w:sdt></w:p>`
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+
+	// fields of embedded structs are promoted
+	type Address struct{ City string }
+	data := struct {
+		Name string
+		*Address
+		Signed time.Time
+	}{"Ann", &Address{"Paris"}, time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)}
+
+	out, err := FillContentControlsBytes(docx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := readPart(t, out, "word/document.xml")
+	if !strings.Contains(content, `<w:rStyle w:val="Strong"/>`) || strings.Contains(content, "PlaceholderText") {
+		t.Errorf("unexpected character styles :\n%s", content)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pp["word/document.xml"], "|"); got != "Ann|Paris|Mon 14 Mar of 2025" {
+		t.Errorf("unexpected text : %q", got)
+	}
+}
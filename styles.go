@@ -0,0 +1,192 @@
+package mydocx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Relationship types of the parts used to render paragraphs.
+const (
+	stylesRelType    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	numberingRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering"
+)
+
+// Get the name of the main document part, or an empty string if none.
+func (pkg *docxPackage) mainPart() string {
+	if names := pkg.names(PartBody); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// Get the name of the part related to the main document with the provided relationship type.
+// Defaults to the conventional name, when the main document has no such relationship.
+func (pkg *docxPackage) relatedPart(relType string, conventional string) (string, error) {
+	return pkg.relationTarget(pkg.mainPart(), relType, conventional)
+}
+
+// Get the name of the part related to a source part with the provided relationship type.
+// The relationships of the package itself (eg : document properties) have an empty source part name.
+// Defaults to the conventional name, when the source part has no such relationship.
+func (pkg *docxPackage) relationTarget(source string, relType string, conventional string) (string, error) {
+	rels, err := pkg.rels(source)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range rels {
+		if r.Type == relType && r.TargetMode != "External" {
+			return r.Target, nil
+		}
+	}
+	return conventional, nil
+}
+
+// paragraphStyle describes a paragraph style defined in styles.xml.
+type paragraphStyle struct {
+	id, name, basedOn string
+	numID             string // numbering instance set by the style, if any
+	ilvl              int    // numbering level set by the style, -1 if unset
+	outline           int    // outline level set by the style (0 based), -1 if unset
+}
+
+// styleSheet holds the paragraph styles of a document.
+type styleSheet struct {
+	styles      map[string]*paragraphStyle // keyed by style id
+	defaultID   string                     // id of the default paragraph style
+	placeholder string                     // id of the character style of the content control placeholders
+}
+
+// Load the style sheet of the package. Returns an empty style sheet if the package has no styles.
+func (pkg *docxPackage) styleSheet() (*styleSheet, error) {
+	ss := &styleSheet{styles: make(map[string]*paragraphStyle), placeholder: "PlaceholderText"}
+	name, err := pkg.relatedPart(stylesRelType, "word/styles.xml")
+	if err != nil {
+		return nil, err
+	}
+	content, err := pkg.read(name)
+	if err != nil || content == nil {
+		return ss, err
+	}
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range root.findAll("style") {
+		if t, _ := s.attrValue(NAMESPACE, "type"); t == "character" && s.child("name") != nil && s.child("name").val() == "Placeholder Text" {
+			ss.placeholder, _ = s.attrValue(NAMESPACE, "styleId") // the id is localized
+		}
+		if t, _ := s.attrValue(NAMESPACE, "type"); t != "paragraph" {
+			continue
+		}
+		ps := &paragraphStyle{ilvl: -1, outline: -1}
+		ps.id, _ = s.attrValue(NAMESPACE, "styleId")
+		if d, _ := s.attrValue(NAMESPACE, "default"); d == "1" || d == "true" {
+			ss.defaultID = ps.id
+		}
+		if n := s.child("name"); n != nil {
+			ps.name = n.val()
+		}
+		if b := s.child("basedOn"); b != nil {
+			ps.basedOn = b.val()
+		}
+		if pPr := s.child("pPr"); pPr != nil {
+			if numPr := pPr.child("numPr"); numPr != nil {
+				ps.numID, ps.ilvl = numPrValues(numPr)
+			}
+			if o := pPr.child("outlineLvl"); o != nil {
+				ps.outline, _ = strconv.Atoi(o.val())
+			}
+		}
+		ss.styles[ps.id] = ps
+	}
+	return ss, nil
+}
+
+// Get the style id of a paragraph, defaulting to the default paragraph style.
+func (ss *styleSheet) paragraphStyleID(p *xnode) string {
+	if pPr := p.child("pPr"); pPr != nil {
+		if s := pPr.child("pStyle"); s != nil {
+			return s.val()
+		}
+	}
+	return ss.defaultID
+}
+
+// Get the numbering set by a style, following the styles it is based on.
+// The level is -1 if the style does not set it.
+func (ss *styleSheet) numbering(id string) (numID string, ilvl int) {
+	ilvl = -1
+	for seen := map[string]bool{}; id != "" && !seen[id]; seen[id] = true {
+		s := ss.styles[id]
+		if s == nil {
+			break
+		}
+		if numID == "" {
+			numID = s.numID
+		}
+		if ilvl < 0 {
+			ilvl = s.ilvl
+		}
+		id = s.basedOn
+	}
+	return numID, ilvl
+}
+
+// Get the numbering instance id and the level of a w:numPr element. The level is -1 if unset.
+func numPrValues(numPr *xnode) (numID string, ilvl int) {
+	ilvl = -1
+	if n := numPr.child("numId"); n != nil {
+		numID = n.val()
+	}
+	if l := numPr.child("ilvl"); l != nil {
+		if v, err := strconv.Atoi(l.val()); err == nil {
+			ilvl = v
+		}
+	}
+	return numID, ilvl
+}
+
+// Get the heading level of a paragraph, from 1 to 9, or 0 for body text.
+// The level is set by the outline level of the paragraph or of its style (following the styles it is based on),
+// or else by the built-in heading styles ("heading 1" ... "heading 9").
+func (ss *styleSheet) headingLevel(p *xnode) int {
+	level := -1
+	if pPr := p.child("pPr"); pPr != nil {
+		if o := pPr.child("outlineLvl"); o != nil {
+			level, _ = strconv.Atoi(o.val())
+		}
+	}
+	id := ss.paragraphStyleID(p)
+	for seen := map[string]bool{}; level < 0 && id != "" && !seen[id]; seen[id] = true {
+		s := ss.styles[id]
+		if s == nil {
+			break
+		}
+		level = s.outline
+		if n, ok := builtinHeading(s.name, s.id); level < 0 && ok {
+			level = n - 1
+		}
+		id = s.basedOn
+	}
+	if level < 0 && ss.styles[ss.paragraphStyleID(p)] == nil { // unknown style, guess from its id
+		if n, ok := builtinHeading("", ss.paragraphStyleID(p)); ok {
+			level = n - 1
+		}
+	}
+	if level < 0 || level > 8 { // level 9 is body text
+		return 0
+	}
+	return level + 1
+}
+
+// Check if a style is a built-in heading style, from its name (eg : "heading 2") or its id (eg : "Heading2").
+// Returns the heading level, from 1 to 9.
+func builtinHeading(name, id string) (int, bool) {
+	for _, s := range []string{strings.ToLower(name), strings.ToLower(id)} {
+		n, ok := strings.CutPrefix(s, "heading")
+		if n = strings.TrimSpace(n); ok && len(n) == 1 && n[0] >= '1' && n[0] <= '9' {
+			return int(n[0] - '0'), true
+		}
+	}
+	return 0, false
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/xml"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -12,6 +14,9 @@ import (
 // Function map for template
 var functionMap template.FuncMap
 
+// Parse errors of a block that is not closed, or not opened, within the paragraph.
+var splitBlock = regexp.MustCompile(`unexpected (EOF|\{\{end\}\}|\{\{else\}\})`)
+
 // register predefined functions
 func init() {
 
@@ -28,16 +33,34 @@ func init() {
 	RegisterTplFunction("date", func() string { return time.Now().Format("2006-01-02") })
 
 	// join takes a slice of strings and returns a single string, joined with the provided delimiter
-	RegisterTplFunction("join", func(args []string, delim string) string { return strings.Join(args, "\n") })
+	RegisterTplFunction("join", func(args []string, delim string) string { return strings.Join(args, delim) })
+
+	// removeEmpty will discard empty paragraphs, for the rest of the document.
+	RegisterTplFunction("removeEmpty", func() string { return setting("removeEmpty") })
+
+	// keepEmpty will always keep empty paragraphs, for the rest of the document.
+	RegisterTplFunction("keepEmpty", func() string { return setting("keepEmpty") })
 
-	// allowDiscard will discard empty paragraphs.
-	RegisterTplFunction("removeEmpty", func() string { REMOVE_EMPTY_PARAGRAPH = true; return "" })
+	// delims, literal and endLiteral are directives, handled before parsing (see tplReader) : they are only executed when misplaced.
+	RegisterTplFunction("delims", func(left, right any) (string, error) {
+		return "", fmt.Errorf("invalid delims directive : use two non-empty string constants, such as {{delims \"[[\" \"]]\"}}")
+	})
+	RegisterTplFunction("literal", func() (string, error) { return "", fmt.Errorf("invalid literal directive") })
+	RegisterTplFunction("endLiteral", func() (string, error) { return "", fmt.Errorf("{{endLiteral}} without {{literal}}") })
 
-	// preventDiscard will always keep empty paragraphs.
-	RegisterTplFunction("keepEmpty", func() string { REMOVE_EMPTY_PARAGRAPH = false; return "" })
+	// link takes an url and a text, and inserts a hyperlink displaying the text (see Link)
+	RegisterTplFunction("link", Link)
+
+	// image takes a png, jpeg or gif file path (or its content as a byte array), and optionally its width and height in points, and inserts the picture (see Image)
+	RegisterTplFunction("image", tplImage)
+
+	// pageBreak takes no argument, and inserts a page break (see PageBreak)
+	RegisterTplFunction("pageBreak", PageBreak)
 }
 
-// Register a new function that will be available when parsing templates.
+// Register a new function that will be available when parsing templates, with the package-level functions,
+// and with the processors created afterwards (see NewProcessor).
+// Functions should be registered before processing documents, since registering is not thread-safe.
 // Empty names or nil functions are ignored.
 // Each function must have either a single return value, or two return values of which the second has type error.
 // In that case, if the second (error) return value evaluates to non-nil during execution,
@@ -62,39 +85,92 @@ func RegisterTplFunction(name string, function any) {
 // * If the template execution result is empty, the paragraph is discarded.
 // * If the template execution result is not empty, it is split around \n into lines and each line is added as a separate paragraph. (you may use the function {{nl}} to gererate new lines)
 // * If an error occurs during template execution, an error message is added as the last paragraph of the result.
+// * A paragraph holding {{delims "[[" "]]"}} changes the delimiters for the next paragraphs (see Processor).
+// * The text from {{literal}} to {{endLiteral}}, that may span several paragraphs, is left untouched.
+// * If, range and with blocks must be closed within their paragraph : blocks spanning several paragraphs,
+// such as the ranges repeating table rows, are reported as errors. Use ExecuteTemplate for them.
+// The typographic quotes, dashes and non-breaking spaces that Word's autocorrect puts within the actions are restored before parsing.
+// Use the WithTemplateErrors option of ModifyText to get the errors as a TemplateErrors error instead.
 func NewTplReplacer(content any) Replacer {
+	return defaultProcessor().NewTplReplacer(content)
+}
+
+// Same as NewTplReplacer, with the template functions and the settings of the Processor.
+// The delimiters set by a {{delims}} directive, and the literal regions, continue over the next paragraphs of the document
+// being modified : the replacer keeps no state of its own, and may modify several documents, concurrently.
+// Called outside of ModifyText, the replacer reads each paragraph on its own.
+func (p *Processor) NewTplReplacer(content any) Replacer {
 	return func(_ string, para string) []string {
-		errmess := ""
-		if para == "" {
-			return []string{""} // leave empty original paragraph untouched.
-		}
+		reader, para := p.resume(para)
+		paras := p.executeParagraph(para, tplSource{text: normalizeActions(reader.source(para))}, content, nil)
+		return p.tplResult(paras, reader)
+	}
+}
+
+// Get the result of the template Replacer : the text of the paragraphs, without the marks of the errors and settings,
+// followed, beyond its length, by the state of the reader at the end of the paragraph, and by the marked text.
+// The modifier uses them to report the errors, apply the settings and resume reading at the next paragraph (see modifier.replaced),
+// while the callers of the Replacer only get plain text.
+func (p *Processor) tplResult(marked []string, r *tplReader) []string {
+	plain := plainText(marked)
+	res := make([]string, 0, len(plain)+1+len(marked))
+	res = append(append(append(res, plain...), r.state(p.reader())), marked...)
+	return res[:len(plain)]
+}
+
+// Get the text of the paragraphs, without the marks of the errors and the settings.
+func plainText(paras []string) []string {
+	res := removeSettings(slices.Clone(paras))
+	for i, s := range res {
+		res[i] = strings.ReplaceAll(s, errorMark, "")
+	}
+	return res
+}
 
-		var res = new(strings.Builder)
+// Execute the template source of a paragraph, as described in NewTplReplacer, and return the resulting paragraphs.
+// The source is usually the paragraph text itself, but may wrap it (to bind the dot or variables, see tplScope) :
+// errors are then located in the paragraph text.
+// The source is parsed into tpl, that may hold additional functions and definitions, or into a new template if tpl is nil.
+func (p *Processor) executeParagraph(para string, source tplSource, content any, tpl *template.Template) []string {
+	errmess := ""
+	if para == "" {
+		return []string{""} // leave empty original paragraph untouched.
+	}
 
-		tpl, err := template.New(NAME + "_template").Funcs(functionMap).Parse(para)
-		if err != nil {
-			errmess = fmt.Sprintf("$$$$$$ ERROR $$$$$ : %v ", err)
-			if VERBOSE {
-				fmt.Println(para, errmess)
-			}
-			return []string{para, errmess}
-		}
-		err = tpl.Execute(res, content)
-		if err != nil {
-			errmess = fmt.Sprintf("$$$$$$ ERROR $$$$$ : %v ", err)
-			if VERBOSE {
-				fmt.Println(para, errmess)
-			}
-			return []string{para, errmess}
+	var res = new(strings.Builder)
+
+	own := tpl == nil // the paragraph is a template of its own, as for NewTplReplacer
+	if own {
+		tpl = template.New(NAME + "_template").Funcs(p.funcs)
+	}
+	tpl, err := tpl.Parse(source.text)
+	if err == nil {
+		err = source.check(tpl)
+	} else if own && splitBlock.MatchString(err.Error()) {
+		err = fmt.Errorf("%v : blocks spanning several paragraphs, such as the ranges repeating table rows, require ExecuteTemplate", err)
+	}
+	if err != nil {
+		errmess = errorParagraph(source.locate(err))
+		if p.Verbose {
+			fmt.Println(para, errmess)
 		}
-		rs := res.String()
-		if rs == "" && errmess == "" {
-			return nil // discard paragraph if result string is empty string and no error message.
+		return []string{para, errmess}
+	}
+	err = tpl.Execute(res, content)
+	if err != nil {
+		errmess = errorParagraph(source.locate(err))
+		if p.Verbose {
+			fmt.Println(para, errmess)
 		}
-		// if not empty, split lines
-		rss := strings.Split(rs, "\n")
-		return rss // discard if result string is empty string.
+		return []string{para, errmess}
+	}
+	rs := res.String()
+	if rs == "" && errmess == "" {
+		return nil // discard paragraph if result string is empty string and no error message.
 	}
+	// if not empty, split lines
+	rss := strings.Split(rs, "\n")
+	return rss // discard if result string is empty string.
 }
 
 // Escape text for inclusion in xml.
@@ -0,0 +1,877 @@
+package mydocx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode/utf8"
+)
+
+// Execute the docx file as a go template, applied to the provided data.
+// Each paragraph is executed as with NewTplReplacer, in addition, if, range and with blocks may span several paragraphs :
+//   - the paragraphs (and tables) between the opening and the end actions are kept, removed or repeated as a whole, keeping their formatting.
+//     Else actions ({{else}}, {{else if ...}}, {{else with ...}}) select alternative paragraphs.
+//     Paragraphs holding only block actions are removed. The text before the opening action, or after the end action, is kept outside the block.
+//   - a table row whose first cell starts with {{range .Items}}, and whose last cell ends with {{end}}, is repeated for each element,
+//     with the element as dot within the cells of the row. The range may also span several rows, from the first cell of the first row
+//     to the last cell of the last row : the group of rows is then repeated. Likewise, if and with blocks keep or remove whole rows.
+//
+// Range and pipeline variables are available as usual within blocks, eg : {{range $i, $item := .Items}}.
+// The template context is shared by the whole document : the containers are executed in reading order (see ExtractContainers),
+// and the variables declared at the top level of a paragraph ({{$total := 0}}), as well as the templates defined
+// with define or block actions, are available to the next paragraphs, until the end of the enclosing block.
+// Assigning a variable ({{$total = ...}}) changes it for the next paragraphs, even within a range, to accumulate values.
+// A define block may span several paragraphs : {{template "name" .}} then produces as many paragraphs.
+// The loop controls ({{break}}, {{continue}}) only apply to the ranges of their own paragraph.
+// The delimiters can be changed, and literal regions left untouched, as with NewTplReplacer.
+// If the targetFile name is empty, the sourceFile will be used, modification will be done in place.
+// Pictures can also be replaced, see WithImages.
+func ExecuteTemplate(sourceFilePath string, data any, targetFilePath string, opts ...Option) error {
+	return defaultProcessor().ExecuteTemplate(sourceFilePath, data, targetFilePath, opts...)
+}
+
+// Same as ExecuteTemplate, with the template functions and the settings of the Processor.
+func (p *Processor) ExecuteTemplate(sourceFilePath string, data any, targetFilePath string, opts ...Option) error {
+	return modifyFile(sourceFilePath, targetFilePath, "execute template", func(in []byte) ([]byte, error) {
+		return p.ExecuteTemplateBytes(in, data, opts...)
+	})
+}
+
+// Same as ExecuteTemplate, but takes a byte array as input and returns the resulting docx as a byte array.
+func ExecuteTemplateBytes(sourceBytes []byte, data any, opts ...Option) ([]byte, error) {
+	return defaultProcessor().ExecuteTemplateBytes(sourceBytes, data, opts...)
+}
+
+// Same as ExecuteTemplateBytes, with the template functions and the settings of the Processor.
+func (p *Processor) ExecuteTemplateBytes(sourceBytes []byte, data any, opts ...Option) ([]byte, error) {
+	conf := newConfig(opts)
+	session := p.session() // settings changed by the templates only apply to this document
+	report := conf.errorReport()
+	pkg, err := openPackage(sourceBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input bytes: %v", err)
+	}
+	pkg.verbose = session.Verbose
+	containers, err := pkg.containers(conf.parts)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(containers))
+	for i, c := range containers {
+		names[i] = c.Name
+	}
+
+	// variables and definitions are shared by all the containers, executed in reading order
+	scope := &tplScope{dot: data, vars: make(map[string]any), lib: p.funcs, root: true}
+	reader := session.reader()
+	defs := template.New(NAME + "_definitions").Funcs(p.funcs)
+	out, err := pkg.rewriteNames(names, func(fname string, root *xnode) error {
+		ex := &tplExecutor{
+			md:      newModifier(pkg, fname, root, nil, session),
+			data:    data,
+			defs:    defs,
+			texts:   make(map[*xnode]string),
+			errs:    make(map[*xnode]error),
+			origins: make(map[*xnode]*xnode),
+			done:    make(map[*xnode]replacement),
+		}
+		for _, para := range root.paragraphs() {
+			ex.texts[para] = normalizeActions(reader.source(paragraphText(para, acceptedView)))
+		}
+		ex.md.reportErrors(report, root)
+		ex.md.markup = conf.markup
+		ex.node(root, scope)
+		if report.stopped() {
+			return report.err()
+		}
+		ex.md.apply(ex.todo)
+		return pkg.replaceImages(fname, root, conf.images)
+	})
+	if err == nil {
+		err = report.err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// tplExecutor executes the paragraphs of a container as templates, expanding the blocks that span several paragraphs.
+type tplExecutor struct {
+	md      *modifier
+	data    any                    // data provided to the templates
+	defs    *template.Template     // templates defined so far, available to the next paragraphs
+	texts   map[*xnode]string      // template source of the paragraphs (see tplReader), without the block actions
+	errs    map[*xnode]error       // errors of the blocks, reported after their opening paragraph
+	origins map[*xnode]*xnode      // source paragraph of the copied paragraphs
+	todo    []replacement          // replacements, in document order
+	done    map[*xnode]replacement // replaced paragraphs, with their result
+}
+
+// Process a node of the tree, and its children, in document order.
+func (ex *tplExecutor) node(n *xnode, scope *tplScope) {
+	switch {
+	case ex.md.report.stopped():
+		return
+	case n.isFallback():
+		ex.todo = append(ex.todo, ex.md.replay(n, ex.done)...)
+		return
+	case n.is("p"):
+		if len(paragraphTexts(n, acceptedView)) > 0 { // make sure we saw at least a run with text !
+			text := ex.text(n)
+			tpl := template.Must(ex.defs.Clone()).New(NAME + "_template").Funcs(scope.funcs())
+			paras := ex.md.proc.applySettings(ex.md.proc.executeParagraph(text, scope.source(text), ex.data, tpl))
+			ex.define(tpl)
+			if err := ex.errs[n]; err != nil {
+				paras = append(paras, errorParagraph(err))
+			}
+			paras = ex.md.checkErrors(ex.origin(n), paras)
+			ex.done[n] = replacement{n, paras, ex.md.proc.RemoveEmptyParagraph}
+			ex.todo = append(ex.todo, ex.done[n])
+		}
+	}
+	ex.sequence(n, append([]*xnode(nil), n.children...), scope)
+}
+
+// Process a sequence of sibling nodes, children of parent, expanding the blocks they contain.
+func (ex *tplExecutor) sequence(parent *xnode, members []*xnode, scope *tplScope) {
+	blocks := ex.blocks(members)
+	for i := 0; i < len(members); i++ {
+		if b, ok := blocks[i]; ok {
+			ex.expand(b, members[b.first:b.last+1], scope)
+			i = b.last
+			continue
+		}
+		ex.node(members[i], scope)
+	}
+	if parent.is("tbl") && parent.child("tr") == nil {
+		parent.remove() // all rows were removed, Word requires at least one
+	}
+}
+
+// Keep the templates defined by a paragraph (with define or block actions), for the next paragraphs.
+func (ex *tplExecutor) define(tpl *template.Template) {
+	for _, t := range tpl.Templates() {
+		if name := t.Name(); t.Tree != nil && name != NAME+"_template" && name != NAME+"_definitions" {
+			ex.defs.AddParseTree(name, t.Tree)
+		}
+	}
+}
+
+// Get the paragraph of the source tree a paragraph was copied from, or the paragraph itself.
+func (ex *tplExecutor) origin(p *xnode) *xnode {
+	if o, ok := ex.origins[p]; ok {
+		return o
+	}
+	return p
+}
+
+// Get the template text of a paragraph.
+func (ex *tplExecutor) text(p *xnode) string {
+	if t, ok := ex.texts[p]; ok {
+		return t
+	}
+	return normalizeActions(paragraphText(p, acceptedView))
+}
+
+// Remove actions from the template text of a paragraph.
+func (ex *tplExecutor) cut(p *xnode, actions ...tplAction) {
+	text := ex.text(p)
+	sort.Slice(actions, func(i, j int) bool { return actions[i].start > actions[j].start })
+	for _, a := range actions {
+		text = text[:a.start] + text[a.end:]
+	}
+	ex.texts[p] = text
+}
+
+// tplMark locates a template action within a sequence of nodes.
+type tplMark struct {
+	member int       // index of the node of the sequence containing the action
+	para   *xnode    // paragraph containing the action
+	action tplAction // the action
+}
+
+// tplBlock is a template block (if, range, with) whose opening and closing actions are in different paragraphs.
+type tplBlock struct {
+	open, end   tplMark
+	elses       []tplMark // else actions of the block, in order
+	first, last int       // nodes of the sequence covered by the block
+}
+
+// Find the outermost blocks of a sequence of sibling nodes, that span several paragraphs, by index of their first node.
+// A block covers the nodes of the sequence from the one containing its opening action, to the one containing its end action.
+// Blocks within a single node of the sequence are left to the processing of that node,
+// except the blocks spanning several cells of a table row, that cover the row.
+func (ex *tplExecutor) blocks(members []*xnode) map[int]tplBlock {
+	res := make(map[int]tplBlock)
+	var stack []tplBlock
+	for mi, m := range members {
+		for _, p := range blockParagraphs(m) {
+			for _, a := range scanActions(ex.text(p)) {
+				mark := tplMark{mi, p, a}
+				switch a.keyword {
+				case "if", "range", "with", "block", "define":
+					stack = append(stack, tplBlock{open: mark, first: mi})
+				case "else":
+					if len(stack) > 0 {
+						stack[len(stack)-1].elses = append(stack[len(stack)-1].elses, mark)
+					}
+				case "end":
+					if len(stack) == 0 {
+						continue
+					}
+					b := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					b.end, b.last = mark, mi
+					if len(stack) == 0 && b.open.para != p && b.expandable(members) {
+						res[b.first] = b
+					}
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Check if the block is expanded at the level of the sequence : an if, range, with or define block, covering several nodes of the sequence,
+// or several cells of a table row.
+func (b tplBlock) expandable(members []*xnode) bool {
+	switch b.open.action.keyword {
+	case "if", "range", "with", "define":
+	default:
+		return false
+	}
+	return b.first != b.last || (members[b.first].is("tr") && b.open.para.ancestor("tc") != b.end.para.ancestor("tc"))
+}
+
+// Get the paragraphs of a node, in document order, excluding the paragraphs nested in other paragraphs (text boxes)
+// and in alternate content fallbacks.
+func blockParagraphs(n *xnode) (res []*xnode) {
+	n.walk(func(c *xnode) bool {
+		switch {
+		case c.isFallback():
+			return false
+		case c.is("p"):
+			res = append(res, c)
+			return false
+		}
+		return true
+	})
+	return res
+}
+
+// Expand a block : the nodes of the selected branch are copied, once for an if or a with block, or for each element of a range,
+// then processed in the scope of the branch. The original nodes are removed.
+// When the block starts or ends within a paragraph of the sequence, the text before its opening action (or after its end action)
+// is kept in a copy of the paragraph, before (or after) the block.
+// A define block is not expanded, but defines a template (see definition).
+// If the block cannot be evaluated, the nodes are processed once, without the block actions,
+// and the error is reported after the opening paragraph.
+func (ex *tplExecutor) expand(b tplBlock, region []*xnode, scope *tplScope) {
+	parent := region[0].parent
+	var before, after []*xnode // copies of the first and last paragraphs, with the text outside the block
+	if first := region[0]; first.is("p") {
+		if text := ex.text(first)[:b.open.action.start]; strings.TrimSpace(text) != "" {
+			before, _ = ex.clone(region[:1])
+			ex.texts[before[0]] = text
+		}
+	}
+	var err error
+	if b.open.action.keyword == "define" {
+		err = ex.definition(b, region)
+	} else {
+		err = b.evaluate(scope, ex.data, func(branch int, s *tplScope) {
+			if before != nil {
+				ex.sequence(parent, before, scope)
+				before = nil
+			}
+			clones := ex.branch(b, region, branch)
+			ex.sequence(parent, clones, s)
+		})
+	}
+	if err != nil {
+		for _, n := range before {
+			n.remove()
+		}
+		ex.errs[b.open.para] = err
+		for _, m := range append([]tplMark{b.open, b.end}, b.elses...) {
+			ex.cut(m.para, m.action)
+		}
+		ex.sequence(parent, region, scope)
+		return
+	}
+	if before != nil {
+		ex.sequence(parent, before, scope) // no branch was selected
+	}
+	if last := region[len(region)-1]; last.is("p") {
+		if text := ex.text(last)[b.end.action.end:]; strings.TrimSpace(text) != "" {
+			after, _ = ex.clone(region[len(region)-1:])
+			ex.texts[after[0]] = text
+			ex.sequence(parent, after, scope)
+		}
+	}
+	for _, n := range region {
+		n.remove()
+	}
+}
+
+// Define the template of a define block spanning several paragraphs : its content is the template text of the paragraphs, joined by new lines,
+// so that {{template "name" .}} produces as many paragraphs, with the formatting of the paragraph where it is used.
+func (ex *tplExecutor) definition(b tplBlock, region []*xnode) error {
+	var lines []string
+	for _, c := range ex.branch(b, region, 0) {
+		for _, p := range blockParagraphs(c) {
+			lines = append(lines, ex.text(p))
+		}
+		c.remove()
+	}
+	_, err := ex.defs.Parse("{{" + b.open.action.body + "}}" + strings.Join(lines, "\n") + "{{end}}")
+	return err
+}
+
+// Evaluate the block within the scope, calling instantiate with the selected branch (0 for the block itself, k for its k-th else),
+// once for an if or a with block, for each element of a range, or not at all if no branch is selected.
+func (b tplBlock) evaluate(scope *tplScope, data any, instantiate func(branch int, s *tplScope)) error {
+	if b.open.action.keyword == "range" {
+		iterations, err := scope.iterate(b.open.action, data)
+		if err != nil {
+			return err
+		}
+		for _, it := range iterations {
+			instantiate(0, it)
+		}
+		if len(iterations) == 0 && len(b.elses) > 0 {
+			instantiate(1, scope.block())
+		}
+		return nil
+	}
+	conditions := append([]tplMark{b.open}, b.elses...)
+	for k, c := range conditions {
+		keyword, pipeline, _ := strings.Cut(c.action.body, " ")
+		if keyword == "else" {
+			if keyword, pipeline, _ = strings.Cut(strings.TrimSpace(pipeline), " "); keyword == "" {
+				instantiate(k, scope.block()) // unconditional else
+				return nil
+			}
+		}
+		s, ok, err := scope.evaluate(keyword, pipeline, data)
+		if err != nil {
+			return err
+		}
+		if ok {
+			instantiate(k, s)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Copy the nodes of a branch of the block, just before the block, and return the copies.
+// A paragraph containing an else action belongs to both branches around the action, with the text before and after the action.
+// Likewise, the text before the opening action of a block, or after its end action, does not belong to the block (see expand).
+// Other nodes containing an else action belong to the next branch if the action is at their beginning, to the previous branch otherwise.
+// The block actions are removed from the template text of the copies, and copied paragraphs left with only blank text are removed.
+func (ex *tplExecutor) branch(b tplBlock, region []*xnode, k int) []*xnode {
+	from, to := 0, len(region)-1
+	if k > 0 {
+		e := b.elses[k-1]
+		if from = e.member - b.first; !region[from].is("p") && !ex.blankBefore(region[from], e) {
+			from++
+		}
+	}
+	if k < len(b.elses) {
+		e := b.elses[k]
+		if to = e.member - b.first; !region[to].is("p") && ex.blankBefore(region[to], e) {
+			to--
+		}
+	}
+	if from > to {
+		return nil
+	}
+	clones, paras := ex.clone(region[from : to+1])
+
+	// template text of the paragraphs containing the block actions, for this branch
+	var bounds []tplMark
+	bounds = append(append(append(bounds, b.open), b.elses...), b.end)
+	for orig, c := range paras {
+		text, lo, hi := ex.text(orig), 0, -1
+		direct := slices.Contains(region, orig)
+		var cuts []tplAction
+		for i, m := range bounds {
+			switch {
+			case m.para != orig:
+			case (i == 0 || i == len(bounds)-1) && !direct:
+				cuts = append(cuts, m.action) // opening and end actions within a larger node : the text around them is kept
+			case i == 0 || i == k:
+				lo = max(lo, m.action.end) // action starting the branch
+			case i == len(bounds)-1 || i == k+1:
+				if hi < 0 || m.action.start < hi {
+					hi = m.action.start // action ending the branch
+				}
+			}
+		}
+		if lo == 0 && hi < 0 && len(cuts) == 0 {
+			continue
+		}
+		if hi < 0 {
+			hi = len(text)
+		}
+		var sb strings.Builder
+		pos := lo
+		sort.Slice(cuts, func(i, j int) bool { return cuts[i].start < cuts[j].start })
+		for _, a := range cuts {
+			if a.start >= lo && a.end <= hi {
+				sb.WriteString(text[pos:a.start])
+				pos = a.end
+			}
+		}
+		sb.WriteString(text[pos:hi])
+		ex.texts[c] = sb.String()
+
+		// remove the paragraphs of the sequence that only held block actions
+		if i := slices.Index(clones, c); i >= 0 && strings.TrimSpace(ex.texts[c]) == "" && !hasGraphics(c) {
+			c.remove()
+			clones = slices.Delete(clones, i, i+1)
+		}
+	}
+	return clones
+}
+
+// Check if there is no text in the node before the action.
+func (ex *tplExecutor) blankBefore(n *xnode, m tplMark) bool {
+	for _, p := range blockParagraphs(n) {
+		if p == m.para {
+			return strings.TrimSpace(ex.text(p)[:m.action.start]) == ""
+		}
+		if strings.TrimSpace(ex.text(p)) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if the node contains pictures, shapes or embedded objects.
+func hasGraphics(n *xnode) bool {
+	found := false
+	n.walk(func(c *xnode) bool {
+		found = found || c.is("drawing") || c.is("pict") || c.is("object")
+		return !found
+	})
+	return found
+}
+
+// Copy the nodes of a region, just before the region. Returns the copies, and the copy of each paragraph of the region.
+// The template texts and the errors of the paragraphs are copied too.
+func (ex *tplExecutor) clone(region []*xnode) ([]*xnode, map[*xnode]*xnode) {
+	var clones []*xnode
+	paras := make(map[*xnode]*xnode)
+	var pair func(o, c *xnode)
+	pair = func(o, c *xnode) {
+		if o.is("p") {
+			paras[o] = c
+			ex.origins[c] = ex.origin(o)
+			if t, ok := ex.texts[o]; ok {
+				ex.texts[c] = t
+			}
+			if err, ok := ex.errs[o]; ok {
+				ex.errs[c] = err
+			}
+		}
+		for i := range o.children {
+			pair(o.children[i], c.children[i])
+		}
+	}
+	for _, n := range region {
+		c := n.clone()
+		pair(n, c)
+		region[0].insertBefore(c)
+		clones = append(clones, c)
+	}
+	return clones, paras
+}
+
+// tplScope is the context in which a template is executed : the dot, and the variables defined by the enclosing blocks
+// and by the previous paragraphs. The $ variable is always the data provided to the template.
+// As in go templates, a variable declared within a block is visible until the end of the block,
+// and assigning a variable declared outside the block changes the variable of the enclosing scope.
+type tplScope struct {
+	dot    any
+	vars   map[string]any   // variables declared in the scope, by name (eg : $item)
+	parent *tplScope        // enclosing scope, nil for the document
+	lib    template.FuncMap // functions available to the templates
+	root   bool             // the dot is the data provided to the template
+}
+
+// Create a child scope, with a new dot.
+func (s *tplScope) child(dot any) *tplScope {
+	return &tplScope{dot: dot, vars: make(map[string]any), parent: s, lib: s.lib}
+}
+
+// Create a child scope for the content of a block keeping the dot.
+func (s *tplScope) block() *tplScope {
+	c := s.child(s.dot)
+	c.root = s.root
+	return c
+}
+
+// Get the value of a variable, from the innermost scope declaring it.
+func (s *tplScope) lookup(name string) any {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// Set the value of a variable, in the innermost scope declaring it.
+func (s *tplScope) assign(name string, v any) {
+	for c := s; c != nil; c = c.parent {
+		if _, ok := c.vars[name]; ok {
+			c.vars[name] = v
+			return
+		}
+	}
+	s.vars[name] = v
+}
+
+// Get the names of the visible variables, sorted.
+func (s *tplScope) names() []string {
+	var res []string
+	for c := s; c != nil; c = c.parent {
+		for name := range c.vars {
+			if !slices.Contains(res, name) {
+				res = append(res, name)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Get the template source executing the text within the scope : the text is wrapped to bind the dot and declare the variables,
+// and to keep the values of the variables declared or assigned at its top level, for the next paragraphs.
+// Define actions are moved out of the wrapper, as they must be at the top level of the source.
+func (s *tplScope) source(text string) tplSource {
+	defines, body, declared := splitTemplate(text)
+	names := s.names()
+	if s.root && len(names) == 0 && len(declared) == 0 {
+		return tplSource{text: text}
+	}
+	src := tplSource{para: text}
+	var sb strings.Builder
+	copyText := func(parts [][2]int) {
+		for _, p := range parts {
+			src.spans = append(src.spans, tplSpan{sb.Len(), p[0], p[1] - p[0]})
+			sb.WriteString(text[p[0]:p[1]])
+		}
+	}
+	copyText(defines)
+	sb.WriteString("{{range __dot}}")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "{{%s := __var %q}}", name, name)
+	}
+	copyText(body)
+	for _, name := range declared {
+		fmt.Fprintf(&sb, "{{__set %q %s}}", name, name)
+	}
+	for _, name := range names {
+		if !slices.Contains(declared, name) {
+			fmt.Fprintf(&sb, "{{__assign %q %s}}", name, name)
+		}
+	}
+	sb.WriteString("{{end}}")
+	src.text = sb.String()
+	return src
+}
+
+// tplSource is the template source executing the text of a paragraph, that may wrap the text (see tplScope.source).
+type tplSource struct {
+	text  string    // template source
+	para  string    // text of the paragraph, when it is wrapped
+	spans []tplSpan // parts of the source copied from the text of the paragraph, in order, nil if the source is not wrapped
+}
+
+// tplSpan is a part of a template source copied from the text of a paragraph.
+type tplSpan struct {
+	at, from, size int // position in the source, position in the text, and length
+}
+
+// Check the parsed source for the loop controls ({{break}}, {{continue}}) that the wrapper would accept outside of the ranges of the text.
+func (src tplSource) check(tpl *template.Template) error {
+	if src.spans == nil {
+		return nil
+	}
+	for _, n := range tpl.Tree.Root.Nodes {
+		if wrapper, ok := n.(*parse.RangeNode); ok {
+			if c := loopControl(wrapper.List); c != nil {
+				loc, _ := tpl.Tree.ErrorContext(c)
+				return fmt.Errorf("template: %s: %s outside {{range}}", loc, c)
+			}
+		}
+	}
+	return nil
+}
+
+// Find a loop control of a list, outside of the ranges of the list, or nil.
+func loopControl(list *parse.ListNode) parse.Node {
+	if list == nil {
+		return nil
+	}
+	for _, n := range list.Nodes {
+		var branch *parse.BranchNode
+		switch n := n.(type) {
+		case *parse.BreakNode, *parse.ContinueNode:
+			return n
+		case *parse.IfNode:
+			branch = &n.BranchNode
+		case *parse.WithNode:
+			branch = &n.BranchNode
+		default:
+			continue
+		}
+		for _, l := range []*parse.ListNode{branch.List, branch.ElseList} {
+			if c := loopControl(l); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// Location of an error in the main template : line, and column if any.
+var sourceLocation = regexp.MustCompile(`^template: ` + NAME + `_template:(\d+)(?::(\d+))?:`)
+
+// Get an error located in the main template with the line and the column of the text of the paragraph, instead of the wrapped source.
+// Locations within the wrapper are moved to the next part of the text.
+func (src tplSource) locate(err error) error {
+	msg := err.Error()
+	m := sourceLocation.FindStringSubmatchIndex(msg)
+	if src.spans == nil || m == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(msg[m[2]:m[3]])
+	col := 0
+	if m[4] >= 0 {
+		col, _ = strconv.Atoi(msg[m[4]:m[5]])
+	}
+	offset := 0
+	for ; line > 1; line-- {
+		i := strings.Index(src.text[offset:], "\n")
+		if i < 0 {
+			break
+		}
+		offset += i + 1
+	}
+	offset += col
+	pos := len(src.para)
+	for _, sp := range src.spans {
+		if offset < sp.at+sp.size {
+			pos = sp.from + max(offset-sp.at, 0)
+			break
+		}
+	}
+	start := strings.LastIndex(src.para[:pos], "\n") + 1
+	loc := strconv.Itoa(1 + strings.Count(src.para[:start], "\n"))
+	if m[4] >= 0 {
+		loc += ":" + strconv.Itoa(pos-start)
+	}
+	return errors.New(msg[:m[2]] + loc + msg[m[1]-1:])
+}
+
+// Get the functions used by the template source of the scope.
+func (s *tplScope) funcs() template.FuncMap {
+	return template.FuncMap{
+		"__dot":    func() []any { return []any{s.dot} },
+		"__var":    s.lookup,
+		"__set":    func(name string, v any) string { s.vars[name] = v; return "" },
+		"__assign": func(name string, v any) string { s.assign(name, v); return "" },
+	}
+}
+
+// Declaration of a variable, eg : $total := 0
+var variableDeclaration = regexp.MustCompile(`^(\$\w+)\s*:=`)
+
+// Split a template text into its top level define blocks and the rest of the text, as lists of ranges of the text,
+// and get the variables declared by the top level actions of the text.
+func splitTemplate(text string) (defines, body [][2]int, declared []string) {
+	depth, pos, start := 0, 0, -1
+	for _, a := range scanActions(text) {
+		switch a.keyword {
+		case "if", "range", "with", "block", "define":
+			if depth == 0 && a.keyword == "define" {
+				start = a.start
+			}
+			depth++
+		case "end":
+			if depth--; depth == 0 && start >= 0 {
+				defines = append(defines, [2]int{start, a.end})
+				body = append(body, [2]int{pos, start})
+				pos, start = a.end, -1
+			}
+		case "":
+			if m := variableDeclaration.FindStringSubmatch(a.body); m != nil && depth == 0 && !slices.Contains(declared, m[1]) {
+				declared = append(declared, m[1])
+			}
+		}
+	}
+	return defines, append(body, [2]int{pos, len(text)}), declared
+}
+
+// Execute a template source, with the functions of the scope and the additional functions, discarding the output.
+func (s *tplScope) run(source string, data any, funcs template.FuncMap) error {
+	all := s.funcs()
+	for k, f := range funcs {
+		all[k] = f
+	}
+	tpl, err := template.New(NAME + "_template").Funcs(s.lib).Funcs(all).Parse(s.source(source).text)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(io.Discard, data)
+}
+
+// Declaration of the variables of a pipeline, eg : $i, $item := .Items
+var pipelineDeclaration = regexp.MustCompile(`^\s*(\$\w*)\s*(?:,\s*(\$\w*)\s*)?:=`)
+
+// Evaluate the range action within the scope, and get the scope of each iteration, with the element as dot and the range variables.
+func (s *tplScope) iterate(action tplAction, data any) ([]*tplScope, error) {
+	pipeline := strings.TrimSpace(strings.TrimPrefix(action.body, "range"))
+	var key, elem string
+	if m := pipelineDeclaration.FindStringSubmatch(pipeline); m != nil {
+		if m[2] == "" {
+			elem = m[1]
+		} else {
+			key, elem = m[1], m[2]
+		}
+		pipeline = pipeline[len(m[0]):]
+	}
+	var res []*tplScope
+	err := s.run("{{range $__k, $__v := "+pipeline+"}}{{__item $__k $__v}}{{end}}", data, template.FuncMap{
+		"__item": func(k, v any) string {
+			it := s.child(v)
+			if key != "" {
+				it.vars[key] = k
+			}
+			if elem != "" {
+				it.vars[elem] = v
+			}
+			res = append(res, it)
+			return ""
+		},
+	})
+	return res, err
+}
+
+// Evaluate the pipeline of an if or a with action within the scope, and get the scope of its content if the pipeline is true (or not empty),
+// with the value of the pipeline as dot for a with action, and the variable declared by the pipeline, if any.
+func (s *tplScope) evaluate(keyword, pipeline string, data any) (*tplScope, bool, error) {
+	if keyword != "if" && keyword != "with" {
+		return nil, false, fmt.Errorf("unexpected %s in block", keyword)
+	}
+	decl := ""
+	if m := pipelineDeclaration.FindStringSubmatch(pipeline); m != nil && m[2] == "" {
+		decl = m[1]
+	}
+	if keyword == "if" && decl == "" {
+		ok := false
+		err := s.run("{{if "+pipeline+"}}{{__take}}{{end}}", data, template.FuncMap{"__take": func() string { ok = true; return "" }})
+		return s.block(), ok, err
+	}
+	var res *tplScope
+	take := "{{__take ."
+	if decl != "" {
+		take += " " + decl
+	}
+	err := s.run("{{"+keyword+" "+pipeline+"}}"+take+"}}{{end}}", data, template.FuncMap{
+		"__take": func(dot any, v ...any) string {
+			if keyword == "if" {
+				res = s.block()
+			} else {
+				res = s.child(dot)
+			}
+			if decl != "" {
+				res.vars[decl] = v[0]
+			}
+			return ""
+		},
+	})
+	return res, res != nil, err
+}
+
+// tplAction is an action found in a template text.
+type tplAction struct {
+	start, end int    // position of the action in the text, delimiters included
+	keyword    string // if, range, with, block, define, else, end, or empty for other actions
+	body       string // content of the action, without delimiters, trim markers and surrounding spaces
+}
+
+// Keywords that open, continue or close a block.
+var tplKeywords = map[string]bool{"if": true, "range": true, "with": true, "block": true, "define": true, "else": true, "end": true}
+
+// Find the actions of a template text, in order. Delimiters within strings and comments are ignored.
+// An unterminated action ends the scan.
+func scanActions(text string) []tplAction {
+	return scanDelimited(text, leftDelim, rightDelim)
+}
+
+// Find the actions of a template text, with the provided delimiters, in order.
+func scanDelimited(text, left, right string) (res []tplAction) {
+	for pos := 0; ; {
+		i := strings.Index(text[pos:], left)
+		if i < 0 {
+			return res
+		}
+		start := pos + i
+		end := actionEnd(text, start+len(left), right)
+		if end < 0 {
+			return res
+		}
+		body := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text[start+len(left):end-len(right)], "-"), "-"))
+		a := tplAction{start: start, end: end, body: body}
+		if word, _, _ := strings.Cut(body, " "); tplKeywords[word] {
+			a.keyword = word
+		}
+		res = append(res, a)
+		pos = end
+	}
+}
+
+// Get the position just after the closing delimiter of the action starting at pos, or -1.
+func actionEnd(text string, pos int, right string) int {
+	for i := pos; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], right):
+			return i + len(right)
+		case strings.HasPrefix(text[i:], "/*"):
+			j := strings.Index(text[i+2:], "*/")
+			if j < 0 {
+				return -1
+			}
+			i += j + 3
+		case text[i] >= utf8.RuneSelf:
+			// string opened by a typographic quote, that autocorrect may close with a straight quote (see normalizeActions)
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if autocorrected[r] == `"` {
+				if end, _ := typographicLiteral(text, i+size); end >= 0 {
+					i = end - 1
+				}
+			}
+		case text[i] == '"' || text[i] == '\'' || text[i] == '`':
+			q := text[i]
+			for i++; i < len(text) && text[i] != q; i++ {
+				if text[i] == '\\' && q != '`' {
+					i++
+				}
+			}
+		}
+	}
+	return -1
+}
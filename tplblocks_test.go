@@ -0,0 +1,230 @@
+package mydocx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Build a table row, with a paragraph per cell.
+func testRow(cells ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`<w:tr>`)
+	for _, c := range cells {
+		sb.WriteString(`<w:tc><w:tcPr><w:tcW w:w="2000" w:type="dxa"/></w:tcPr>` + testPara(c) + `</w:tc>`)
+	}
+	sb.WriteString(`</w:tr>`)
+	return sb.String()
+}
+
+// Build a table from its rows.
+func testTable(rows ...string) string {
+	return `<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/></w:tblPr>` + strings.Join(rows, "") + `</w:tbl>`
+}
+
+type testItem struct {
+	Name string
+	Qty  int
+}
+
+func TestTableRows(t *testing.T) {
+
+	body := testPara("Invoice {{.Number}}") +
+		testTable(
+			testRow("Item", "Quantity"),
+			testRow("{{range $i, $item := .Items}}{{$i}}. {{.Name}}", "{{$item.Qty}} for {{$.Number}}{{end}}"),
+			testRow("Total", "{{.Total}}"),
+		) +
+		testTable(
+			testRow("{{range .Items}}Name", "{{.Name}}"),
+			testRow("Quantity", "{{.Qty}}{{end}}"),
+		) +
+		testTable(testRow("{{range .None}}{{.}}", "{{end}}")) +
+		testTable(testRow("{{range .Total.Items}}Item", "{{end}}"))
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	data := map[string]any{
+		"Number": "F-42",
+		"Items":  []testItem{{"Apple", 3}, {"Pear", 5}},
+		"Total":  8,
+		"None":   []string{},
+	}
+	out, err := ExecuteTemplateBytes(docx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Invoice F-42",
+		"Item", "Quantity", "0. Apple", "3 for F-42", "1. Pear", "5 for F-42", "Total", "8",
+		"Name", "Apple", "Quantity", "3", "Name", "Pear", "Quantity", "5",
+		"Item", "$$$$$$ ERROR $$$$$ : can't evaluate field Items", "", // range errors are reported after the opening paragraph
+	}
+	got := pp["word/document.xml"]
+	if len(got) != len(want) {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+	for i := range want {
+		if prefix, detail, isErr := strings.Cut(want[i], " : "); isErr && strings.HasPrefix(got[i], prefix) && strings.Contains(got[i], detail) {
+			continue
+		}
+		if got[i] != want[i] {
+			t.Errorf("paragraph %d : got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	pkg, err := openPackage(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := pkg.read("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(content)
+	if n := strings.Count(doc, "<w:tbl>"); n != 3 {
+		t.Errorf("the table without rows should be removed, got %d tables", n)
+	}
+	if n := strings.Count(doc, `<w:tcW w:w="2000" w:type="dxa"/>`); n != 2*8+2 {
+		t.Errorf("unexpected number of cells : %d", n)
+	}
+
+	// the replacer executes each paragraph on its own : the ranges repeating rows are reported
+	_, err = ModifyTextBytes(docx, NewTplReplacer(data), WithTemplateErrors())
+	var errs TemplateErrors
+	if !errors.As(err, &errs) || len(errs) != 8 || !strings.Contains(errs[0].Message, "require ExecuteTemplate") {
+		t.Errorf("unexpected errors : %v", err)
+	}
+}
+
+func TestParagraphBlocks(t *testing.T) {
+
+	body := testPara("Contract for {{.Name}}") +
+		testPara("{{if .Vip}}") + testPara("Gold clause") + testPara("{{else if .Partner}}") + testPara("Partner clause") + testPara("{{else}}") + testPara("Standard clause") + testPara("{{end}}") +
+		testPara("{{range $i, $o := .Options}}Option {{$i}} :") + testPara("{{.}}{{if eq . \"Insurance\"}}") + testPara("(recommended){{end}}") + testPara("{{end}}") +
+		testPara("{{with .Address}}Address : {{.City}}") + testPara("{{.Country}}{{else}}No address{{end}}") +
+		testPara("{{range .None}}") + testPara("{{.}}") + testPara("{{else}}No options") + testPara("{{end}}") +
+		testPara("Signed by {{.Name}}") +
+		testTable(testRow("{{if .Vip}}VIP", "{{.Name}}"), testRow("{{else}}Regular", "{{.Name}}{{end}}"))
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	type address struct{ City, Country string }
+	check := func(data any, want ...string) {
+		t.Helper()
+		out, err := ExecuteTemplateBytes(docx, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pp, err := ExtractTextBytes(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pp["word/document.xml"]; strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("got  %q\nwant %q", got, want)
+		}
+	}
+	check(map[string]any{"Name": "John", "Vip": true, "Options": []string{"Insurance", "Delivery"}, "Address": address{"Paris", "France"}},
+		"Contract for John", "Gold clause",
+		"Option 0 :", "Insurance", "(recommended)", "Option 1 :", "Delivery",
+		"Address : Paris", "France", "No options", "Signed by John", "VIP", "John")
+	check(map[string]any{"Name": "Jane", "Partner": true},
+		"Contract for Jane", "Partner clause", "No address", "No options", "Signed by Jane", "Regular", "Jane")
+	check(map[string]any{"Name": "Joe", "None": []int{1, 2}},
+		"Contract for Joe", "Standard clause", "No address", "1", "2", "Signed by Joe", "Regular", "Joe")
+}
+
+func TestTemplateContext(t *testing.T) {
+
+	body := testPara(`{{$names := ""}}{{$count := len .Items}}`) +
+		testPara(`{{define "line"}}{{.Name}} x{{.Qty}}{{end}}Order of {{$count}} items`) +
+		testPara(`{{range .Items}}`) +
+		testPara(`{{template "line" .}}{{$names = printf "%s%s;" $names .Name}}{{$local := .Name}}`) +
+		testPara(`{{end}}`) +
+		testPara(`Names: {{$names}}`) +
+		testPara(`{{define "signature"}}`) + testPara(`Regards,`) + testPara(`{{.Sender}}`) + testPara(`{{end}}`) +
+		testPara(`{{template "signature" .}}`) +
+		testPara(`{{$local}}`) // variables declared within a block are not visible after it
+	notes := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<w:footnotes ` + testNamespaces + `><w:footnote w:id="1">` + testPara(`{{$names}} {{template "line" index .Items 0}}`) + `</w:footnote></w:footnotes>`
+
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/notes.xml":    notes,
+		"word/document.xml": testDocument(body),
+	})
+	data := map[string]any{
+		"Items":  []testItem{{"Apple", 3}, {"Pear", 5}},
+		"Sender": "Bob",
+	}
+	out, err := ExecuteTemplateBytes(docx, data, WithParts(AllParts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out, WithParts(AllParts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Order of 2 items", "Apple x3", "Pear x5", "Names: Apple;Pear;", "Regards,", "Bob",
+		`{{$local}}`, `$$$$$$ ERROR $$$$$ : undefined variable "$local"`}
+	got := pp["word/document.xml"]
+	if len(got) != len(want) {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+	for i := range want {
+		if prefix, detail, isErr := strings.Cut(want[i], " : "); isErr && strings.HasPrefix(got[i], prefix) && strings.Contains(got[i], detail) {
+			continue
+		}
+		if got[i] != want[i] {
+			t.Errorf("paragraph %d : got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// the footnotes are executed after the main document
+	if got := pp["word/notes.xml"]; len(got) != 1 || got[0] != "Apple;Pear; Apple x3" {
+		t.Errorf("unexpected footnotes : %q", got)
+	}
+}
+
+func TestTemplateWrapper(t *testing.T) {
+
+	// paragraphs following a variable declaration are wrapped to bind the variable
+	docx := makeDocx(t, map[string]string{
+		contentTypesName: testContentTypes,
+		"word/document.xml": testDocument(testPara("{{$n := 1}}") + testPara("{{break}}") +
+			testPara("{{range .Items}}{{if eq . 2}}{{continue}}{{end}}{{.}}{{end}} ok") + testPara("Hello {{$n}} {{.Name.Foo}}")),
+	})
+	data := map[string]any{"Name": "John", "Items": []int{1, 2, 3}}
+	_, err := ExecuteTemplateBytes(docx, data, WithTemplateErrors())
+	var errs TemplateErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("unexpected errors : %v", err)
+	}
+	if errs[0].Paragraph != 1 || errs[0].Message != "template: mydocx_template:1:2: {{break}} outside {{range}}" {
+		t.Errorf("unexpected error : %+v", errs[0])
+	}
+	// errors are located in the text of the paragraph, as when it is not wrapped
+	want := NewTplReplacer(data)("", "Hello {{ 1}} {{.Name.Foo}}")
+	if len(want) != 2 || errs[1].Paragraph != 3 || errs[1].Message != strings.TrimSuffix(strings.TrimPrefix(strings.ReplaceAll(want[1], errorMark, ""), errorBanner), " ") {
+		t.Errorf("unexpected error : %+v, want %q", errs[1], want)
+	}
+
+	out, err := ExecuteTemplateBytes(docx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) < 3 || got[2] != "13 ok" {
+		t.Errorf("unexpected text : %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+package mydocx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Prefix of the paragraphs reporting a template error, as inserted in the document by default (see NewTplReplacer).
+const errorBanner = "$$$$$$ ERROR $$$$$ : "
+
+// Mark of the paragraphs reporting a template error, that the text of the document and the data cannot forge (see inlineObject).
+var errorMark = inlineObject("error")
+
+// Get the text of the paragraph reporting a template error, marked for checkErrors.
+func errorParagraph(err error) string {
+	return fmt.Sprintf(errorBanner+"%v ", err) + errorMark
+}
+
+// TemplateError is a template error, found while processing a paragraph, when errors are reported (see WithTemplateErrors).
+type TemplateError struct {
+	// Name of the container of the paragraph (eg : word/document.xml)
+	Container string
+	// Index of the paragraph within its container, in the source document, as listed by ExtractText
+	Paragraph int
+	// Text of the paragraph, in the source document
+	Text string
+	// Error message, from parsing or executing the template
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s, paragraph %d %q : %s", e.Container, e.Paragraph, e.Text, e.Message)
+}
+
+// TemplateErrors lists the template errors of a document, in processing order.
+type TemplateErrors []*TemplateError
+
+func (e TemplateErrors) Error() string {
+	msg := make([]string, len(e))
+	for i, te := range e {
+		msg[i] = te.Error()
+	}
+	return fmt.Sprintf("%d template error(s) : %s", len(e), strings.Join(msg, " ; "))
+}
+
+// Unwrap returns the errors of the list, for errors.Is and errors.As.
+func (e TemplateErrors) Unwrap() []error {
+	res := make([]error, len(e))
+	for i, te := range e {
+		res[i] = te
+	}
+	return res
+}
+
+// errorReport collects the template errors of a document.
+type errorReport struct {
+	failFast bool // stop at the first error
+	errs     TemplateErrors
+}
+
+// Check if the processing should stop.
+func (r *errorReport) stopped() bool {
+	return r != nil && r.failFast && len(r.errs) > 0
+}
+
+// Get the collected errors, or nil.
+func (r *errorReport) err() error {
+	if r == nil || len(r.errs) == 0 {
+		return nil
+	}
+	return r.errs
+}
+
+// Record the template errors found in the replaced text of a paragraph, when errors are reported, and remove them from the text.
+// Otherwise, the errors are kept as paragraphs of the text, without their mark.
+// The paragraph p is the paragraph of the source tree, that the replaced paragraph may be a copy of.
+func (md *modifier) checkErrors(p *xnode, paras []string) []string {
+	res := paras[:0:0]
+	for _, s := range paras {
+		if !strings.Contains(s, errorMark) {
+			res = append(res, s)
+			continue
+		}
+		s = strings.ReplaceAll(s, errorMark, "")
+		if md.report == nil {
+			res = append(res, s)
+			continue
+		}
+		index, found := md.index[p]
+		if !found {
+			index = -1
+		}
+		md.report.errs = append(md.report.errs, &TemplateError{
+			Container: md.container,
+			Paragraph: index,
+			Text:      paragraphText(p, acceptedView),
+			Message:   strings.TrimSuffix(strings.TrimPrefix(s, errorBanner), " "),
+		})
+	}
+	return res
+}
@@ -0,0 +1,102 @@
+package mydocx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTemplateErrors(t *testing.T) {
+
+	body := testPara("Hello {{.Name}}") + testPara("{{.Name.Missing}}") + testPara("") + testPara("{{if .Name}}unterminated") +
+		testPara("{{range .Name}}") + testPara("item") + testPara("{{end}}")
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+	data := map[string]any{"Name": "John"}
+
+	// by default, errors are inserted in the document
+	out, err := ModifyTextBytes(docx, NewTplReplacer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := ExtractTextBytes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) < 3 || !strings.HasPrefix(got[2], errorBanner) {
+		t.Errorf("unexpected text : %q", got)
+	}
+
+	// reported errors, with their paragraph
+	check := func(err error, want ...int) {
+		t.Helper()
+		var errs TemplateErrors
+		if !errors.As(err, &errs) || len(errs) != len(want) {
+			t.Fatalf("unexpected errors : %v", err)
+		}
+		for i, e := range errs {
+			if e.Container != "word/document.xml" || e.Paragraph != want[i] || e.Message == "" || strings.Contains(e.Message, errorBanner) {
+				t.Errorf("unexpected error : %+v", e)
+			}
+		}
+		var te *TemplateError
+		if !errors.As(err, &te) || te != errs[0] {
+			t.Errorf("errors.As should find the first error")
+		}
+	}
+	out, err = ModifyTextBytes(docx, NewTplReplacer(data), WithTemplateErrors())
+	if out != nil {
+		t.Errorf("no document should be produced")
+	}
+	check(err, 1, 3, 4, 6) // each paragraph is an independent template
+
+	out, err = ModifyTextBytes(docx, NewTplReplacer(data), WithFailFast())
+	if out != nil {
+		t.Errorf("no document should be produced")
+	}
+	check(err, 1)
+
+	// with blocks spanning paragraphs, errors are reported on the opening paragraph
+	docx = makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(testPara("{{.Name.Missing}}") + testPara("{{range .Name}}") + testPara("item") + testPara("{{end}}")),
+	})
+	_, err = ExecuteTemplateBytes(docx, data, WithTemplateErrors())
+	check(err, 0, 1)
+	var errs TemplateErrors
+	if errors.As(err, &errs) && (errs[1].Text != "{{range .Name}}" || !strings.Contains(errs[1].Message, "range can't iterate over John")) {
+		t.Errorf("unexpected error : %+v", errs[1])
+	}
+
+	// no error
+	if _, err = ExecuteTemplateBytes(docx, data, WithParts(PartHeaders), WithFailFast()); err != nil {
+		t.Error(err)
+	}
+
+	// called directly, the replacer returns plain text, without the marks of the errors and settings
+	replace := NewTplReplacer(data)
+	for _, tc := range []struct {
+		para string
+		want int
+	}{{"{{.Name.Missing}}", 2}, {"{{keepEmpty}}", 0}, {"{{removeEmpty}}Hello {{.Name}}", 1}} {
+		got := replace("", tc.para)
+		if len(got) != tc.want || strings.ContainsAny(strings.Join(got, ""), "\uE000\uE001\uE002") {
+			t.Errorf("replace(%q) = %q", tc.para, got)
+		}
+	}
+
+	// a text looking like an error is not reported
+	fake := errorBanner + "not an error"
+	out, err = ModifyTextBytes(docx, func(_, s string) []string { return []string{fake} }, WithTemplateErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp, err = ExtractTextBytes(out); err != nil {
+		t.Fatal(err)
+	}
+	if got := pp["word/document.xml"]; len(got) != 4 || got[0] != fake {
+		t.Errorf("unexpected text : %q", got)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
+	"os"
+	"reflect"
+	"strings"
 )
 
 // Helper function to read a file from a zip archive
@@ -17,6 +20,36 @@ func readFile(f *zip.File) ([]byte, error) {
 	return io.ReadAll(rc)
 }
 
+// Read a docx file, and extract information from its content.
+func fromFile[T any](sourceFilePath string, extract func([]byte, ...Option) (T, error), opts []Option) (T, error) {
+	data, err := os.ReadFile(sourceFilePath)
+	if err != nil {
+		var none T
+		return none, fmt.Errorf("failed to read input file: %v", err)
+	}
+	return extract(data, opts...)
+}
+
+// Read a docx file, modify its content, and write the result to the target file, or in place if the target file name is empty.
+// The action (eg : "modify text") describes the modification in the returned errors.
+func modifyFile(sourceFilePath string, targetFilePath string, action string, modify func([]byte) ([]byte, error)) error {
+	if targetFilePath == "" {
+		targetFilePath = sourceFilePath
+	}
+	in, err := os.ReadFile(sourceFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %v", err)
+	}
+	out, err := modify(in)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	if err := os.WriteFile(targetFilePath, out, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
 // Helper function to copy unmodified files to the new zip
 func copyFileToZip(zipWriter *zip.Writer, file *zip.File) error {
 	readCloser, err := file.Open()
@@ -35,9 +68,9 @@ func copyFileToZip(zipWriter *zip.Writer, file *zip.File) error {
 }
 
 // debug helper
-func (cd *custDecoder) debug(message ...any) {
+func (md *modifier) debug(message ...any) {
 
-	if !debugflag {
+	if md.proc == nil || !md.proc.Debug {
 		return
 	}
 
@@ -46,29 +79,80 @@ func (cd *custDecoder) debug(message ...any) {
 		fmt.Print(m)
 		fmt.Print(" ")
 	}
-	fmt.Println("rcontent = ", (string)(cd.rcontent))
-	cd.dumpRes()
-
+	fmt.Println("container = ", md.container)
 }
 
-// debug helper
-func (cd *custDecoder) dumpRes() {
+// Find the value named by key in data.
+// Data can be a map with string keys, or a struct, or a pointer to those.
+// Struct fields are matched by their `docx:"name"` tag first, then by their name, ignoring case.
+// The key may be a dotted path (eg : Customer.Name) to reach nested values.
+func lookupValue(data any, key string) (any, bool) {
+	if v, ok := lookupPath(reflect.ValueOf(data), []string{key}); ok {
+		return v, true
+	}
+	if strings.Contains(key, ".") {
+		return lookupPath(reflect.ValueOf(data), strings.Split(key, "."))
+	}
+	return nil, false
+}
 
-	if !debugflag {
-		return
+// Follow the path of keys within v, see lookupValue.
+func lookupPath(v reflect.Value, path []string) (any, bool) {
+	for _, key := range path {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			v = v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		case reflect.Struct:
+			v = structField(v, key)
+		default:
+			return nil, false
+		}
+		if !v.IsValid() {
+			return nil, false
+		}
+	}
+	if !v.CanInterface() {
+		return nil, false
 	}
+	return v.Interface(), true
+}
 
-	fmt.Println("\nRes =")
-	for i, s := range cd.res {
-		h := ""
-		if i == cd.curPara {
-			h = h + "p "
+// Get the exported struct field matching key, by tag, exact name or name ignoring case.
+// Fields of embedded structs are promoted, as in text/template. Returns an invalid value if not found.
+func structField(v reflect.Value, key string) reflect.Value {
+	var byName []int
+	exact := false
+	for _, f := range reflect.VisibleFields(v.Type()) {
+		if !f.IsExported() {
+			continue
+		}
+		if tag, _, _ := strings.Cut(f.Tag.Get("docx"), ","); tag == key {
+			return fieldByIndex(v, f.Index)
 		}
-		if i == cd.firstRunText {
-			h = h + "r0 "
+		if (f.Name == key && !exact) || (byName == nil && strings.EqualFold(f.Name, key)) {
+			byName, exact = f.Index, f.Name == key
 		}
-		h = (h + "                ")[:8]
-		fmt.Printf("%d:%s%q\n", i, h, (string)(s))
 	}
-	fmt.Println()
+	if byName == nil {
+		return reflect.Value{}
+	}
+	return fieldByIndex(v, byName)
+}
+
+// Get a nested struct field, or an invalid value if an embedded pointer is nil.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	f, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return f
 }
@@ -0,0 +1,464 @@
+package mydocx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Check the templates of a docx file, without executing them, and report their problems as a list of TemplateError :
+//   - syntax errors, including unknown functions and unbalanced blocks,
+//   - actions damaged by Word's autocorrect or formatting : unterminated or split delimiters (typographic quotes and dashes within actions are restored, see NewTplReplacer),
+//   - templates used but never defined,
+//   - if sample is not nil, fields that the type of sample does not provide (eg : {{.Customer.Nmae}}), following ranges, with and variables.
+//
+// Each paragraph is checked as NewTplReplacer executes it : as a template of its own. See ValidateTemplateBlocks for ExecuteTemplate.
+// Fields of maps, interfaces, and values returned by functions cannot be checked.
+// The returned list is empty if no problem was found. The error reports an invalid docx file.
+func ValidateTemplate(docx []byte, sample any, opts ...Option) (TemplateErrors, error) {
+	return defaultProcessor().ValidateTemplate(docx, sample, opts...)
+}
+
+// Same as ValidateTemplate, with the template functions of the Processor.
+func (p *Processor) ValidateTemplate(docx []byte, sample any, opts ...Option) (TemplateErrors, error) {
+	v, err := p.parseDocument(docx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range v.lines {
+		v.balance([]int{i})
+		if tpl, err := v.parse(p.funcs, []int{i}); err != nil {
+			v.report(i, "%v", err)
+		} else {
+			v.check(tpl, reflect.TypeOf(sample), []int{i})
+		}
+	}
+	return v.sorted(), nil
+}
+
+// Same as ValidateTemplate, but checks the document as ExecuteTemplate executes it :
+// blocks may span several paragraphs, and the containers share their variables.
+func ValidateTemplateBlocks(docx []byte, sample any, opts ...Option) (TemplateErrors, error) {
+	return defaultProcessor().ValidateTemplateBlocks(docx, sample, opts...)
+}
+
+// Same as ValidateTemplateBlocks, with the template functions of the Processor.
+func (p *Processor) ValidateTemplateBlocks(docx []byte, sample any, opts ...Option) (TemplateErrors, error) {
+	v, err := p.parseDocument(docx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if tpl, err := v.parseBlocks(p.funcs); err != nil {
+		v.report(0, "%v", err) // not located : reported on the first paragraph
+	} else {
+		v.check(tpl, reflect.TypeOf(sample), v.all())
+	}
+	return v.sorted(), nil
+}
+
+// Read the template sources of the selected containers of a docx file, one line per paragraph, and check their delimiters.
+// The problems found are recorded by the returned validator, the parts in error are removed from the sources.
+func (p *Processor) parseDocument(docx []byte, opts []Option) (*validator, error) {
+	conf := newConfig(opts)
+	pkg, err := openPackage(docx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open docx file: %v", err)
+	}
+	containers, err := pkg.containers(conf.parts)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	reader := p.reader()
+	for _, c := range containers {
+		content, err := pkg.read(c.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", c.Name, err)
+		}
+		texts, err := extractParagraphsView(content, acceptedView, nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from %s : %v", c.Name, err)
+		}
+		for i, t := range texts {
+			v.lines = append(v.lines, tplLine{c.Name, i, t, normalizeActions(reader.source(strings.ReplaceAll(t, "\n", " ")))})
+		}
+	}
+	for i := range v.lines {
+		v.lint(i)
+	}
+	return v, nil
+}
+
+// tplLine is a paragraph of the document, as a line of the template source checked by the validator.
+type tplLine struct {
+	container string
+	index     int    // index of the paragraph in its container
+	text      string // text of the paragraph
+	source    string // template source, without the parts already reported
+}
+
+// validator checks the template source of a document, one line per paragraph.
+type validator struct {
+	lines []tplLine
+	errs  TemplateErrors
+}
+
+// Get the indexes of all the lines.
+func (v *validator) all() []int {
+	res := make([]int, len(v.lines))
+	for i := range res {
+		res[i] = i
+	}
+	return res
+}
+
+// Parse the template sources of the document as a single template, as ExecuteTemplate executes it.
+func (v *validator) parseBlocks(funcs template.FuncMap) (*template.Template, error) {
+	lines := v.all()
+	v.balance(lines)
+	return v.parse(funcs, lines)
+}
+
+// Get the problems, in document order.
+func (v *validator) sorted() TemplateErrors {
+	order := make(map[string]int)
+	for i, l := range v.lines {
+		order[fmt.Sprint(l.container, l.index)] = i
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return order[fmt.Sprint(v.errs[i].Container, v.errs[i].Paragraph)] < order[fmt.Sprint(v.errs[j].Container, v.errs[j].Paragraph)]
+	})
+	return v.errs
+}
+
+// Report a problem of a line.
+func (v *validator) report(line int, format string, args ...any) {
+	l := v.lines[line]
+	v.errs = append(v.errs, &TemplateError{Container: l.container, Paragraph: l.index, Text: l.text, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check the delimiters and the actions of a line, for damages due to Word's autocorrect or formatting.
+// The damaged parts are removed from the source, so that they are not reported again.
+func (v *validator) lint(line int) {
+	src := v.lines[line].source
+	if !strings.Contains(src, "{") && !strings.Contains(src, "}") {
+		return
+	}
+	// delimiters outside of the actions
+	outside, pos := "", 0
+	for _, a := range scanActions(src) {
+		outside += src[pos:a.start]
+		pos = a.end
+	}
+	rest := src[pos:]
+	switch {
+	case strings.Contains(rest, "{{"):
+		v.report(line, "unterminated action %s : {{ without }}", strings.TrimSpace(rest[strings.Index(rest, "{{"):]))
+		src = src[:pos] + rest[:strings.Index(rest, "{{")]
+	case splitDelimiter.MatchString(outside + rest):
+		v.report(line, "split delimiter %q : remove the space between the braces", splitDelimiter.FindString(outside+rest))
+	case strings.Contains(outside+rest, "}}"):
+		v.report(line, "}} without {{")
+	}
+	v.lines[line].source = src
+}
+
+// Delimiter split by a space, or by autocorrect.
+var splitDelimiter = regexp.MustCompile(`\{\s+\{|\}\s+\}`)
+
+// Check that the blocks are balanced over the provided lines, and remove the unbalanced actions from their sources.
+func (v *validator) balance(lines []int) {
+	type opener struct {
+		line   int
+		action tplAction
+	}
+	var stack []opener
+	cut := make(map[int][]tplAction)
+	for _, i := range lines {
+		for _, a := range scanActions(v.lines[i].source) {
+			switch a.keyword {
+			case "if", "range", "with", "block", "define":
+				stack = append(stack, opener{i, a})
+			case "else":
+				if len(stack) == 0 {
+					v.report(i, "{{%s}} outside of any block", a.body)
+					cut[i] = append(cut[i], a)
+				}
+			case "end":
+				if len(stack) == 0 {
+					v.report(i, "{{end}} without opening block")
+					cut[i] = append(cut[i], a)
+					continue
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	for _, o := range stack {
+		v.report(o.line, "{{%s}} is never closed by {{end}}", o.action.body)
+		cut[o.line] = append(cut[o.line], o.action)
+	}
+	for i, actions := range cut {
+		src := v.lines[i].source
+		sort.Slice(actions, func(a, b int) bool { return actions[a].start > actions[b].start })
+		for _, a := range actions {
+			src = src[:a.start] + src[a.end:]
+		}
+		v.lines[i].source = src
+	}
+}
+
+// Location of a parse error, or of a node : template name, line and column.
+var errorLine = regexp.MustCompile(`^(?:template: )?[^:]*:(\d+):`)
+
+// Parse the template sources of the provided lines, as a single template with one line per paragraph,
+// reporting each syntax error on its line, that is then ignored.
+// Returns the parsed template, or the error that could not be located on a line.
+func (v *validator) parse(funcs template.FuncMap, lines []int) (*template.Template, error) {
+	for {
+		var sb strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(v.lines[line].source)
+		}
+		tpl, err := template.New(NAME + "_template").Funcs(funcs).Parse(sb.String())
+		if err == nil {
+			return tpl, nil
+		}
+		m := errorLine.FindStringSubmatch(err.Error())
+		if m == nil {
+			return nil, err
+		}
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(lines) || v.lines[lines[n-1]].source == "" {
+			return nil, err
+		}
+		msg := err.Error()
+		if i := strings.Index(msg, m[0]); i >= 0 {
+			msg = strings.TrimSpace(msg[i+len(m[0]):])
+		}
+		v.report(lines[n-1], "%s", msg)
+		v.lines[lines[n-1]].source = ""
+	}
+}
+
+// Check the template references (fields and templates), starting from the main template parsed from the provided lines,
+// with dot and $ of the type of the sample data (nil if unknown).
+func (v *validator) check(tpl *template.Template, sample reflect.Type, lines []int) {
+	c := &typeChecker{v: v, lines: lines, tpl: tpl, visited: make(map[string]bool)}
+	c.node(tpl.Tree, tpl.Tree.Root, sample, map[string]reflect.Type{"$": sample})
+}
+
+// typeChecker follows the types of dot and of the variables through a parse tree, to find the fields that do not exist.
+// A nil type is unknown : its fields are not checked.
+type typeChecker struct {
+	v       *validator
+	lines   []int // lines of the validator, by line of the template
+	tpl     *template.Template
+	visited map[string]bool // templates already checked, by name and type of dot
+}
+
+// Report a problem at the location of a node.
+func (c *typeChecker) report(tree *parse.Tree, n parse.Node, format string, args ...any) {
+	loc, _ := tree.ErrorContext(n)
+	if m := errorLine.FindStringSubmatch(loc); m != nil {
+		if line, err := strconv.Atoi(m[1]); err == nil && line >= 1 && line <= len(c.lines) {
+			c.v.report(c.lines[line-1], format, args...)
+		}
+	}
+}
+
+// Check a node, with the types of dot and of the visible variables.
+func (c *typeChecker) node(tree *parse.Tree, n parse.Node, dot reflect.Type, vars map[string]reflect.Type) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.node(tree, child, dot, vars) // variables declared by an action stay visible until the end of the list
+		}
+	case *parse.ActionNode:
+		c.declare(n.Pipe, c.pipe(tree, n.Pipe, dot, vars), vars)
+	case *parse.IfNode:
+		inner := copyTypes(vars)
+		c.declare(n.Pipe, c.pipe(tree, n.Pipe, dot, inner), inner)
+		c.node(tree, n.List, dot, inner)
+		c.node(tree, n.ElseList, dot, copyTypes(vars))
+	case *parse.WithNode:
+		inner := copyTypes(vars)
+		t := c.pipe(tree, n.Pipe, dot, inner)
+		c.declare(n.Pipe, t, inner)
+		c.node(tree, n.List, t, inner)
+		c.node(tree, n.ElseList, dot, copyTypes(vars))
+	case *parse.RangeNode:
+		inner := copyTypes(vars)
+		key, elem := rangeTypes(c.pipe(tree, n.Pipe, dot, inner))
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			inner[n.Pipe.Decl[0].Ident[0]], inner[n.Pipe.Decl[1].Ident[0]] = key, elem
+		}
+		c.node(tree, n.List, elem, inner)
+		c.node(tree, n.ElseList, dot, copyTypes(vars))
+	case *parse.TemplateNode:
+		var t reflect.Type
+		if n.Pipe != nil {
+			t = c.pipe(tree, n.Pipe, dot, vars)
+		}
+		def := c.tpl.Lookup(n.Name)
+		if def == nil || def.Tree == nil {
+			c.report(tree, n, "template %q is not defined", n.Name)
+			return
+		}
+		key := fmt.Sprintf("%s %v", n.Name, t)
+		if c.visited[key] {
+			return
+		}
+		c.visited[key] = true
+		c.node(def.Tree, def.Tree.Root, t, map[string]reflect.Type{"$": t})
+	}
+}
+
+// Record the type of the variable declared by a pipeline, if any.
+func (c *typeChecker) declare(pipe *parse.PipeNode, t reflect.Type, vars map[string]reflect.Type) {
+	if pipe != nil && len(pipe.Decl) == 1 && !pipe.IsAssign {
+		vars[pipe.Decl[0].Ident[0]] = t
+	}
+}
+
+// Check the arguments of a pipeline, and get the type of its value.
+func (c *typeChecker) pipe(tree *parse.Tree, pipe *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	if pipe == nil {
+		return nil
+	}
+	var res reflect.Type
+	for _, cmd := range pipe.Cmds {
+		res = nil
+		for i, arg := range cmd.Args {
+			t := c.arg(tree, arg, dot, vars)
+			if _, field := arg.(*parse.FieldNode); i == 0 && (len(cmd.Args) == 1 || field) { // value, or method call with arguments
+				res = t
+			}
+		}
+	}
+	return res
+}
+
+// Check an argument, and get the type of its value.
+func (c *typeChecker) arg(tree *parse.Tree, n parse.Node, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	switch n := n.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.fields(tree, n, dot, n.Ident)
+	case *parse.VariableNode:
+		return c.fields(tree, n, vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		return c.fields(tree, n, c.arg(tree, n.Node, dot, vars), n.Field)
+	case *parse.PipeNode:
+		return c.pipe(tree, n, dot, vars)
+	}
+	return nil
+}
+
+// Follow a chain of field names from a type, reporting the first missing field, and get the type of the last one.
+func (c *typeChecker) fields(tree *parse.Tree, n parse.Node, t reflect.Type, names []string) reflect.Type {
+	for _, name := range names {
+		if t == nil {
+			return nil
+		}
+		next, ok := fieldType(t, name)
+		if !ok {
+			c.report(tree, n, "can't evaluate field %s in type %v", name, t)
+			return nil
+		}
+		t = next
+	}
+	return t
+}
+
+// Get the type of a field, or of the result of a method, of a type, as the templates evaluate it.
+// The type is nil if unknown, such as the elements of a map of interfaces.
+func fieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := t.MethodByName(name); ok && t.Kind() != reflect.Interface {
+		return methodResult(m.Type)
+	}
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface {
+		if m, ok := reflect.PointerTo(t).MethodByName(name); ok {
+			return methodResult(m.Type)
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil, true
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, false
+		}
+		return known(t.Elem()), true
+	case reflect.Struct:
+		if f, ok := t.FieldByName(name); ok && f.IsExported() {
+			return known(f.Type), true
+		}
+	}
+	return nil, false
+}
+
+// Get the type of the result of a method, nil if it has none.
+func methodResult(m reflect.Type) (reflect.Type, bool) {
+	if m.NumOut() == 0 {
+		return nil, true
+	}
+	return known(m.Out(0)), true
+}
+
+// Get the types of the key and of the elements of a range over a value of type t, nil if unknown.
+func rangeTypes(t reflect.Type) (key, elem reflect.Type) {
+	if t == nil {
+		return nil, nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0), known(t.Elem())
+	case reflect.Map:
+		return known(t.Key()), known(t.Elem())
+	case reflect.Chan:
+		return nil, known(t.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return nil, t
+	}
+	return nil, nil
+}
+
+// Get the type, or nil for interfaces, whose dynamic type is unknown.
+func known(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
+
+// Copy the types of the variables, for a nested scope.
+func copyTypes(vars map[string]reflect.Type) map[string]reflect.Type {
+	res := make(map[string]reflect.Type, len(vars))
+	for k, t := range vars {
+		res[k] = t
+	}
+	return res
+}
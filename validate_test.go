@@ -0,0 +1,100 @@
+package mydocx
+
+import (
+	"strings"
+	"testing"
+)
+
+type testCustomer struct {
+	Name    string
+	Address struct{ City string }
+	Orders  []testItem
+	Tags    map[string]string
+	Extra   any
+}
+
+func (c testCustomer) Greeting(polite bool) string { return "Hello " + c.Name }
+
+func TestValidateTemplate(t *testing.T) {
+
+	body := testPara("Dear {{.Name}}, {{.Greeting true}} from {{.Address.City}} {{.Tags.vip}} {{.Extra.Anything}}") + // valid
+		testPara("{{.Nmae}} and {{.Address.Town}}") + // missing fields
+		testPara("{{range $i, $o := .Orders}}{{$o.Name}} {{.Qty}} {{.Price}}{{end}}") + // range element
+		testPara("{{with .Address}}{{.City}}{{.Zip}}{{end}}") + // with
+		testPara("{{if .Name}}") + testPara("{{$.Missing}}") + testPara("{{end}}") + // block spanning paragraphs
+		testPara("{{printf “%s–%s” .Name .Nmae}}") + // autocorrect is restored
+		testPara("Total : {{.Total") + // unterminated
+		testPara("{{shout .Name}}") + // unknown function
+		testPara("{{define \"sig\"}}{{.Name}} {{.Phone}}{{end}}{{template \"sig\" .}} {{template \"other\"}}") +
+		testPara("{{end}}") + testPara("{{range .Orders}}") + // unbalanced
+		testPara("{ {.Name}} and “quoted” text") // split delimiter, quotes outside actions are fine
+	docx := makeDocx(t, map[string]string{
+		contentTypesName:    testContentTypes,
+		"word/document.xml": testDocument(body),
+	})
+
+	// as ExecuteTemplate executes the document
+	errs, err := ValidateTemplateBlocks(docx, testCustomer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		paragraph int
+		message   string
+	}{
+		{1, "can't evaluate field Nmae in type mydocx.testCustomer"},
+		{1, "can't evaluate field Town in type struct { City string }"},
+		{2, "can't evaluate field Price in type mydocx.testItem"},
+		{3, "can't evaluate field Zip in type struct { City string }"},
+		{5, "can't evaluate field Missing in type mydocx.testCustomer"},
+		{7, "can't evaluate field Nmae in type mydocx.testCustomer"},
+		{8, "unterminated action {{.Total : {{ without }}"},
+		{9, `function "shout" not defined`},
+		{10, "can't evaluate field Phone in type mydocx.testCustomer"},
+		{10, `template "other" is not defined`},
+		{11, "{{end}} without opening block"},
+		{12, "{{range .Orders}} is never closed by {{end}}"},
+		{13, `split delimiter "{ {" : remove the space between the braces`},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d :\n%v", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		if e := errs[i]; e.Container != "word/document.xml" || e.Paragraph != w.paragraph || !strings.Contains(e.Message, w.message) {
+			t.Errorf("error %d : got %+v, want %+v", i, e, w)
+		}
+	}
+
+	// as NewTplReplacer executes each paragraph : blocks cannot span paragraphs
+	errs, err = ValidateTemplate(docx, testCustomer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != len(want)+2 {
+		t.Fatalf("got %d errors, want %d :\n%v", len(errs), len(want)+2, errs)
+	}
+	for i, w := range []struct {
+		paragraph int
+		message   string
+	}{{4, "{{if .Name}} is never closed by {{end}}"}, {5, "can't evaluate field Missing"}, {6, "{{end}} without opening block"}} {
+		if e := errs[i+4]; e.Paragraph != w.paragraph || !strings.Contains(e.Message, w.message) {
+			t.Errorf("error %d : got %+v, want %+v", i+4, e, w)
+		}
+	}
+
+	// without sample data, fields are not checked
+	errs, err = ValidateTemplateBlocks(docx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 6 {
+		t.Errorf("unexpected errors : %v", errs)
+	}
+
+	// functions of a processor
+	p := NewProcessor()
+	p.RegisterTplFunction("shout", strings.ToUpper)
+	if errs, _ = p.ValidateTemplateBlocks(docx, nil); len(errs) != 5 {
+		t.Errorf("unexpected errors : %v", errs)
+	}
+}
@@ -24,14 +24,42 @@ import "regexp"
 // v0.4.3 add word-level diff analysis with Diff() and PrettyPrint() functions for comparing original vs accepted text with LLM-friendly output
 // v0.4.4 add DiffAnalyse() convenience function for one-line DOCX diff analysis
 // v0.5.0 remove external dependencies - implement internal LCS-based diff algorithm with full Unicode support
+// v0.5.1 select processed containers per call, based on their content type (WithParts option)
+// v0.5.2 add ExtractContainers, listing containers in reading order with header/footer section and type
+// v0.6.0 redesign extract and modify on a lightweight xml tree. Handle text boxes and alternate content without duplicates.
+// v0.6.1 list content controls (w:sdt) and fill them by tag from a map or a struct
+// v0.6.2 extract and replace the text of bookmarks
+// v0.6.3 compute list numbering labels, optionally prefixed to extracted paragraphs (WithNumbering option)
+// v0.6.4 extract the outline of the document, as a tree of sections under their headings
+// v0.6.5 field awareness : extract field results or codes, preserve fields when modifying, update simple fields
+// v0.6.6 mail merge compatibility : replace MERGEFIELD fields by their formatted value (MergeFields)
+// v0.6.7 extract hyperlinks with their resolved target, insert hyperlinks from templates ({{link}}) or replacers (Link)
+// v0.6.8 insert png, jpeg or gif images from templates ({{image}}) or replacers (Image, ImageBytes)
+// v0.6.9 replace placeholder pictures, identified by their alt text or name, keeping their size and position (WithImages, ReplaceImages)
+// v0.7.0 execute the whole document as a template (ExecuteTemplate), repeating table rows from ranges
+// v0.7.1 if, range and with blocks spanning several paragraphs or table rows, with else branches (ExecuteTemplate)
+// v0.7.2 document-scoped template context : variables and definitions shared across paragraphs and containers (ExecuteTemplate)
+// v0.7.3 add Processor, holding settings and template functions per instance. {{keepEmpty}} and {{removeEmpty}} only apply to the current document.
+// v0.7.4 report template errors with their container and paragraph, as a typed multi-error, optionally failing fast (WithTemplateErrors, WithFailFast)
+// v0.7.5 add ValidateTemplate, checking templates for syntax errors, autocorrect damages and fields missing from the data type
+// v0.7.6 add ExtractTemplateFields, with TemplateSchema and TemplateStruct describing the data expected by templates
+// v0.7.7 undo Word's autocorrect (typographic quotes, dashes, non-breaking spaces) within template actions, before parsing
+// v0.7.8 configurable template delimiters (Processor, {{delims}} directive) and literal regions ({{literal}} ... {{endLiteral}})
+// v0.7.9 template function library : numbers, currencies, dates, amounts in words (en, fr), plurals, case, padding, default, get. Fix join ignoring its delimiter
+// v0.8.0 inline formatting : {{bold}}, {{italic}} and {{underline}}, and **markup** with WithMarkup, rendered as runs inheriting the paragraph run properties
+// v0.8.1 structured content from replacers : styled paragraphs, formatted runs, list items, tables and page breaks (NewContent, NewContentReplacer). Add {{pageBreak}}
+// v0.8.2 add ModifyTextContext, with a ContextReplacer receiving the paragraph context : index, style, heading and list levels, table cell, neighbours, section
 
 const (
 	AUTHOR      = "Xavier Gandillot"
 	DESCRIPTION = "A simple library to modify Microsoft Word .docx documents with go templates"
 	NAME        = "mydocx"
-	VERSION     = "0.5.0"
+	VERSION     = "0.8.2"
 	COPYRIGHT   = "(c) Xavier Gandillot 2024,2025"
 	NAMESPACE   = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+	// namespace of relationship ids (r:id, r:embed, ...)
+	RELATIONSHIPS_NAMESPACE = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
 )
 
 var (
@@ -40,12 +68,13 @@ var (
 
 	// If set to true, paragraphs that become empty after replacement are removed (paragraphs that were initially empty, before replacement, are never removed).
 	// If false, paragraphs that become empty are kept.
-	// Use the functions {{removeEmpty}}  or {{keepEmpty}} in the source word document to set this value.
-	// You may also set this variable directly from code.
+	// Use the functions {{removeEmpty}}  or {{keepEmpty}} in the source word document to change this setting, for the rest of the document only.
+	// You may also set this variable directly from code, it then applies to the next calls of the package-level functions.
+	// Use a Processor to process documents concurrently with different settings.
 	// Default is true.
 	REMOVE_EMPTY_PARAGRAPH bool = true
 
-	// pattern to select which xml container will be transformed
+	// pattern to select which xml container will be transformed, only used when the package has no [Content_Types].xml
 	containerPattern = regexp.MustCompile(`^(word/document\.xml)|(word/footer[0-9]+\.xml)|(word/header[0-9]+\.xml)$`)
 
 	// set to true for detailed debugging information
@@ -0,0 +1,192 @@
+package mydocx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Word date and time picture items, mapped to the corresponding go layout elements.
+// Longest items are listed first, since they are matched greedily.
+var wordDateItems = []struct{ word, layout string }{
+	{"yyyy", "2006"}, {"YYYY", "2006"}, {"yy", "06"}, {"YY", "06"},
+	{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"}, {"M", "1"},
+	{"dddd", "Monday"}, {"DDDD", "Monday"}, {"ddd", "Mon"}, {"DDD", "Mon"}, {"dd", "02"}, {"DD", "02"}, {"d", "2"}, {"D", "2"},
+	{"HH", "15"}, {"H", "15"}, {"hh", "03"}, {"h", "3"},
+	{"mm", "04"}, {"m", "4"}, {"ss", "05"}, {"s", "5"},
+	{"AM/PM", "PM"}, {"am/pm", "pm"},
+}
+
+// Format a date with a Word date picture (eg : dd/MM/yyyy, as used by date content controls and \@ field switches).
+// Each picture item is formatted with the corresponding go layout element. Other characters, and text between single quotes,
+// are copied literally : they are never interpreted as go layout elements.
+func formatWordDate(t time.Time, picture string) string {
+	var sb strings.Builder
+	for i := 0; i < len(picture); {
+		if picture[i] == '\'' { // quoted literal text
+			j := strings.IndexByte(picture[i+1:], '\'')
+			if j < 0 {
+				sb.WriteString(picture[i+1:])
+				break
+			}
+			sb.WriteString(picture[i+1 : i+1+j])
+			i += j + 2
+			continue
+		}
+		matched := false
+		for _, item := range wordDateItems {
+			if strings.HasPrefix(picture[i:], item.word) {
+				sb.WriteString(t.Format(item.layout))
+				i += len(item.word)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			sb.WriteByte(picture[i])
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// Layouts accepted when a date is provided as a string.
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// Convert a value into a date, if possible.
+// Accepts time.Time, *time.Time, and strings using one of the dateLayouts.
+func toDate(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Convert a value into a string, for insertion in the document.
+// Nil values produce an empty string.
+func toText(value any) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Format a number with a Word numeric picture (eg : #,##0.00 or "€ #,##0"), as used by \# field switches.
+// 0 is a required digit, # an optional digit, the comma groups thousands and the dot separates decimals.
+// Text before and after the digits is copied literally.
+func formatWordNumber(f float64, picture string) string {
+	start := strings.IndexAny(picture, "0#")
+	if start < 0 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	end := strings.LastIndexAny(picture, "0#") + 1
+	prefix, digits, suffix := picture[:start], picture[start:end], picture[end:]
+	if start > 0 && picture[start-1] == '-' { // sign placeholder
+		prefix = picture[:start-1]
+	}
+	intPart, decPart, _ := strings.Cut(digits, ".")
+	decimals := strings.Count(decPart, "0") + strings.Count(decPart, "#")
+	minDigits := strings.Count(intPart, "0")
+
+	scale := math.Pow10(decimals) // round half away from zero, as Word does
+	s := strconv.FormatFloat(math.Round(math.Abs(f)*scale)/scale, 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "0" && minDigits == 0 {
+		whole = ""
+	}
+	for len(whole) < minDigits {
+		whole = "0" + whole
+	}
+	if strings.Contains(intPart, ",") {
+		whole = groupThousands(whole, ",")
+	}
+	frac = strings.TrimRight(frac, "0")
+	for len(frac) < strings.Count(decPart, "0") {
+		frac += "0"
+	}
+	if frac != "" {
+		whole += "." + frac
+	}
+	if f < 0 && whole != "" && strings.Trim(whole, "0.,") != "" {
+		whole = "-" + whole
+	}
+	return prefix + whole + suffix
+}
+
+// Insert the separator between groups of thousands of a string of digits.
+func groupThousands(digits string, sep string) string {
+	var sb strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// Apply a Word format switch (\* Upper, \* FirstCap, \* roman, \* Ordinal ...) to a field value.
+// MERGEFORMAT, CHARFORMAT and unsupported formats leave the value unchanged.
+func applyFormatSwitch(text string, format string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	switch strings.ToLower(format) {
+	case "upper":
+		return strings.ToUpper(text)
+	case "lower":
+		return strings.ToLower(text)
+	case "firstcap":
+		for i, r := range text {
+			return text[:i] + string(unicode.ToUpper(r)) + strings.ToLower(text[i+len(string(r)):])
+		}
+		return text
+	case "caps":
+		words := strings.Fields(strings.ToLower(text))
+		for i, w := range words {
+			r := []rune(w)
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+		return strings.Join(words, " ")
+	case "roman":
+		if err == nil {
+			if format[0] == 'r' {
+				return toRoman(n)
+			}
+			return strings.ToUpper(toRoman(n))
+		}
+	case "alphabetic":
+		if err == nil {
+			if format[0] == 'a' {
+				return toLetter(n)
+			}
+			return strings.ToUpper(toLetter(n))
+		}
+	case "ordinal":
+		if err == nil {
+			return strconv.Itoa(n) + ordinalSuffix(n)
+		}
+	case "arabic":
+		if err == nil {
+			return strconv.Itoa(n)
+		}
+	case "hex":
+		if err == nil {
+			return strings.ToUpper(strconv.FormatInt(int64(n), 16))
+		}
+	}
+	return text
+}
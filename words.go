@@ -0,0 +1,120 @@
+package mydocx
+
+import "strings"
+
+// Scales of the numbers in words, from the largest.
+var scales = []uint64{1e12, 1e9, 1e6, 1e3}
+
+var englishUnits = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+	"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+var englishTens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+var englishScales = []string{"trillion", "billion", "million", "thousand"}
+
+// Get an integer in english words (eg : one thousand two hundred thirty-four).
+func englishWords(n uint64) string {
+	if n == 0 {
+		return englishUnits[0]
+	}
+	var parts []string
+	for i, scale := range scales {
+		if q := n / scale; q > 0 {
+			parts = append(parts, englishHundreds(q)+" "+englishScales[i])
+			n %= scale
+		}
+	}
+	if n > 0 {
+		parts = append(parts, englishHundreds(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Get an integer from 1 to 999 in english words.
+func englishHundreds(n uint64) string {
+	var parts []string
+	if h := n / 100; h > 0 {
+		parts = append(parts, englishUnits[h]+" hundred")
+	}
+	switch n %= 100; {
+	case n >= 20 && n%10 > 0:
+		parts = append(parts, englishTens[n/10]+"-"+englishUnits[n%10])
+	case n >= 20:
+		parts = append(parts, englishTens[n/10])
+	case n > 0:
+		parts = append(parts, englishUnits[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+var frenchUnits = []string{"zéro", "un", "deux", "trois", "quatre", "cinq", "six", "sept", "huit", "neuf", "dix",
+	"onze", "douze", "treize", "quatorze", "quinze", "seize"}
+var frenchTens = []string{"", "", "vingt", "trente", "quarante", "cinquante", "soixante", "soixante", "quatre-vingt", "quatre-vingt"}
+var frenchScales = [][2]string{{"billion", "billions"}, {"milliard", "milliards"}, {"million", "millions"}}
+
+// Get an integer in french words, with the traditional spelling (eg : mille deux cent trente-quatre, quatre-vingts, deux cents millions).
+func frenchWords(n uint64) string {
+	if n == 0 {
+		return frenchUnits[0]
+	}
+	var parts []string
+	for i, scale := range scales[:3] {
+		if q := n / scale; q > 0 {
+			name := frenchScales[i][0]
+			if q > 1 {
+				name = frenchScales[i][1]
+			}
+			parts = append(parts, frenchHundreds(q, true)+" "+name) // deux cents millions : million is a noun
+			n %= scale
+		}
+	}
+	switch q := n / 1000; {
+	case q == 1:
+		parts = append(parts, "mille")
+	case q > 1:
+		parts = append(parts, frenchHundreds(q, false)+" mille") // deux cent mille : mille is invariable
+	}
+	if n %= 1000; n > 0 {
+		parts = append(parts, frenchHundreds(n, true))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Get an integer from 1 to 999 in french words.
+// The multiples of cent and quatre-vingt take an s if they end the number, or precede a noun.
+func frenchHundreds(n uint64, final bool) string {
+	var parts []string
+	switch h := n / 100; {
+	case h == 1:
+		parts = append(parts, "cent")
+	case h > 1 && n%100 == 0 && final:
+		parts = append(parts, frenchUnits[h]+" cents")
+	case h > 1:
+		parts = append(parts, frenchUnits[h]+" cent")
+	}
+	if n %= 100; n > 0 {
+		parts = append(parts, frenchTensWords(n, final))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Get an integer from 1 to 99 in french words.
+func frenchTensWords(n uint64, final bool) string {
+	if n < 17 {
+		return frenchUnits[n]
+	}
+	t, u := n/10, n%10
+	if t == 1 || t == 7 || t == 9 {
+		u += 10 // dix-sept, soixante-dix, quatre-vingt-onze
+	}
+	tens := frenchTens[t]
+	switch {
+	case t == 1:
+		return "dix-" + frenchUnits[u-10]
+	case u == 0 && t == 8 && final:
+		return tens + "s"
+	case u == 0:
+		return tens
+	case (u == 1 || u == 11) && t != 8 && t != 9:
+		return tens + " et " + frenchUnits[u] // vingt et un, soixante et onze
+	}
+	return tens + "-" + frenchTensWords(u, final)
+}
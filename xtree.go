@@ -0,0 +1,366 @@
+package mydocx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Namespace of the markup compatibility elements (mc:AlternateContent, mc:Choice, mc:Fallback).
+const mcNamespace = "http://schemas.openxmlformats.org/markup-compatibility/2006"
+
+// xnode is a node of a lightweight xml tree, built from the content of a container.
+// Each node keeps the raw bytes it was parsed from, so that an unmodified tree is written back unchanged,
+// byte for byte. Only the nodes that are actually modified are re-generated.
+type xnode struct {
+	name     xml.Name   // resolved name of an element, empty for other nodes
+	attr     []xml.Attr // resolved attributes of an element
+	raw      []byte     // raw start tag of an element, or raw content of other nodes
+	end      []byte     // raw end tag of an element, nil for self-closing elements
+	chardata bool       // true for text nodes
+	text     []byte     // unescaped content of text nodes
+	children []*xnode
+	parent   *xnode
+}
+
+// Parse the content of a container into a tree.
+// The returned root node is not an element, its children are the top level tokens (xml declaration, root element, ...).
+func parseTree(content []byte) (*xnode, error) {
+	root := new(xnode)
+	cur := root
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	var last int64
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		next := dec.InputOffset()
+		raw := content[last:next]
+		last = next
+		switch t := tok.(type) {
+		case xml.StartElement:
+			t = t.Copy()
+			n := &xnode{name: t.Name, attr: t.Attr, raw: raw, parent: cur}
+			cur.children = append(cur.children, n)
+			cur = n
+		case xml.EndElement:
+			if len(raw) > 0 { // self-closing elements produce an empty end token
+				cur.end = raw
+			}
+			cur = cur.parent
+		case xml.CharData:
+			cur.children = append(cur.children, &xnode{raw: raw, chardata: true, text: bytes.Clone(t), parent: cur})
+		default: // comments, processing instructions, directives
+			cur.children = append(cur.children, &xnode{raw: raw, parent: cur})
+		}
+	}
+}
+
+// Well known namespace prefixes, declared when parsing generated xml fragments.
+var fragmentNamespaces = map[string]string{
+	"w":   NAMESPACE,
+	"r":   RELATIONSHIPS_NAMESPACE,
+	"mc":  mcNamespace,
+	"w14": "http://schemas.microsoft.com/office/word/2010/wordml",
+	"wp":  "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing",
+	"a":   "http://schemas.openxmlformats.org/drawingml/2006/main",
+	"pic": "http://schemas.openxmlformats.org/drawingml/2006/picture",
+}
+
+// Parse a generated xml fragment, using the well known namespace prefixes (w:, r:, wp:, ...).
+// The generated raw bytes use these prefixes, that Word always declares on the root element of its containers.
+func parseFragment(fragment string) ([]*xnode, error) {
+	var decl strings.Builder
+	for prefix, space := range fragmentNamespaces {
+		decl.WriteString(" xmlns:" + prefix + `="` + space + `"`)
+	}
+	head := "<mydocx" + decl.String() + ">"
+	content := []byte(head + fragment + "</mydocx>")
+	root, err := parseTree(content)
+	if err != nil {
+		return nil, err
+	}
+	nodes := root.children[0].children
+	for _, n := range nodes {
+		n.parent = nil
+	}
+	return nodes, nil
+}
+
+// Write the tree back as xml.
+func (n *xnode) write(buf *bytes.Buffer) {
+	buf.Write(n.raw)
+	for _, c := range n.children {
+		c.write(buf)
+	}
+	buf.Write(n.end)
+}
+
+// Get the xml bytes of the tree.
+func (n *xnode) bytes() []byte {
+	buf := new(bytes.Buffer)
+	n.write(buf)
+	return buf.Bytes()
+}
+
+// Check if the node is an element of the main wordprocessing namespace, with the provided local name.
+func (n *xnode) is(local string) bool {
+	return n.name.Local == local && n.name.Space == NAMESPACE
+}
+
+// Check if the node is an element with the provided namespace and local name.
+func (n *xnode) isNS(space, local string) bool {
+	return n.name.Local == local && n.name.Space == space
+}
+
+// Check if the node is an element (not text, comment, ...).
+func (n *xnode) isElement() bool {
+	return n.name.Local != ""
+}
+
+// Get the value of an attribute, matching its namespace and local name.
+// The namespace is ignored if empty.
+func (n *xnode) attrValue(space, local string) (string, bool) {
+	for _, a := range n.attr {
+		if a.Name.Local == local && (space == "" || a.Name.Space == space) {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Get the value of the w:val attribute, or an empty string.
+func (n *xnode) val() string {
+	v, _ := n.attrValue(NAMESPACE, "val")
+	return v
+}
+
+// Get the first child element from the main namespace with the provided local name, or nil.
+func (n *xnode) child(local string) *xnode {
+	for _, c := range n.children {
+		if c.is(local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Get the first child element with the provided namespace and local name, or nil.
+func (n *xnode) childNS(space, local string) *xnode {
+	for _, c := range n.children {
+		if c.isNS(space, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Get the first ancestor from the main namespace with the provided local name, or nil.
+func (n *xnode) ancestor(local string) *xnode {
+	for a := n.parent; a != nil; a = a.parent {
+		if a.is(local) {
+			return a
+		}
+	}
+	return nil
+}
+
+// Walk the tree in document order (pre-order), starting with the node itself.
+// Children of a node are not visited if f returns false.
+func (n *xnode) walk(f func(*xnode) bool) {
+	if !f(n) {
+		return
+	}
+	// children may be modified by f, iterate over a copy
+	for _, c := range append([]*xnode(nil), n.children...) {
+		c.walk(f)
+	}
+}
+
+// Find all the descendant elements (and the node itself) from the main namespace with the provided local name, in document order.
+func (n *xnode) findAll(local string) (res []*xnode) {
+	n.walk(func(c *xnode) bool {
+		if c.is(local) {
+			res = append(res, c)
+		}
+		return true
+	})
+	return res
+}
+
+// Deep copy of the node. The copy has no parent.
+func (n *xnode) clone() *xnode {
+	c := *n
+	c.parent = nil
+	c.attr = append([]xml.Attr(nil), n.attr...)
+	c.children = make([]*xnode, len(n.children))
+	for i, ch := range n.children {
+		c.children[i] = ch.clone()
+		c.children[i].parent = &c
+	}
+	return &c
+}
+
+// Index of the node within its parent children, or -1.
+func (n *xnode) index() int {
+	if n.parent == nil {
+		return -1
+	}
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove the node from its parent.
+func (n *xnode) remove() {
+	if i := n.index(); i >= 0 {
+		n.parent.children = append(n.parent.children[:i], n.parent.children[i+1:]...)
+	}
+	n.parent = nil
+}
+
+// Insert nodes as siblings, just after the node.
+func (n *xnode) insertAfter(nodes ...*xnode) {
+	i := n.index()
+	if i < 0 {
+		return
+	}
+	n.parent.insertChildren(i+1, nodes...)
+}
+
+// Insert nodes as siblings, just before the node.
+func (n *xnode) insertBefore(nodes ...*xnode) {
+	i := n.index()
+	if i < 0 {
+		return
+	}
+	n.parent.insertChildren(i, nodes...)
+}
+
+// Insert nodes as children, at the provided position.
+func (n *xnode) insertChildren(pos int, nodes ...*xnode) {
+	for _, c := range nodes {
+		c.parent = n
+	}
+	n.children = append(n.children[:pos], append(append([]*xnode(nil), nodes...), n.children[pos:]...)...)
+}
+
+// Append nodes as the last children.
+func (n *xnode) appendChildren(nodes ...*xnode) {
+	n.insertChildren(len(n.children), nodes...)
+}
+
+// Get the unescaped text content of the node, concatenating all its descendant text nodes.
+func (n *xnode) textContent() string {
+	var sb strings.Builder
+	n.walk(func(c *xnode) bool {
+		if c.chardata {
+			sb.Write(c.text)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// Replace the content of an element with the provided text, that will be escaped.
+// A self-closing element is expanded into a start and an end tag.
+func (n *xnode) setText(text string) {
+	if n.end == nil {
+		head := bytes.TrimSuffix(bytes.TrimSpace(n.raw[:len(n.raw)-1]), []byte("/"))
+		n.raw = append(bytes.Clone(head), '>') // never append in place, raw shares the container content
+		n.end = []byte("</" + n.qname() + ">")
+	}
+	n.children = nil
+	if text != "" {
+		n.children = []*xnode{{raw: xmlEscape([]byte(text)), chardata: true, text: []byte(text), parent: n}}
+	}
+}
+
+// Get the qualified (prefixed) name of an element, as written in its raw start tag.
+func (n *xnode) qname() string {
+	raw := bytes.TrimPrefix(n.raw, []byte("<"))
+	if i := bytes.IndexAny(raw, " \t\r\n/>"); i >= 0 {
+		raw = raw[:i]
+	}
+	return string(raw)
+}
+
+// Set the value of an attribute in the raw start tag of an element, using its qualified name (eg : w:val).
+// The attribute is added if it does not exist yet.
+func (n *xnode) setAttr(qname string, value string) {
+	val := new(bytes.Buffer)
+	xml.EscapeText(val, []byte(value))
+	re := regexp.MustCompile(`(\s` + regexp.QuoteMeta(qname) + `\s*=\s*)("[^"]*"|'[^']*')`)
+	if re.Match(n.raw) {
+		n.raw = re.ReplaceAllLiteral(n.raw, nil) // remove, then add below
+	}
+	tail := ">"
+	if bytes.HasSuffix(n.raw, []byte("/>")) {
+		tail = "/>"
+	}
+	head := bytes.TrimSpace(bytes.TrimSuffix(n.raw, []byte(tail)))
+	n.raw = append(append([]byte(nil), head...), []byte(" "+qname+`="`+val.String()+`"`+tail)...)
+
+	// keep the resolved attributes consistent
+	local, space := qname, ""
+	if i := strings.Index(qname, ":"); i >= 0 {
+		local, space = qname[i+1:], fragmentNamespaces[qname[:i]]
+		if qname[:i] == "xml" {
+			space = "http://www.w3.org/XML/1998/namespace"
+		}
+	}
+	for i, a := range n.attr {
+		if a.Name.Local == local && a.Name.Space == space {
+			n.attr[i].Value = value
+			return
+		}
+	}
+	n.attr = append(n.attr, xml.Attr{Name: xml.Name{Space: space, Local: local}, Value: value})
+}
+
+// Check if the node is a markup compatibility fallback.
+// Fallbacks duplicate the content of the preferred choice (eg : a VML copy of a DrawingML text box), they are ignored.
+func (n *xnode) isFallback() bool {
+	return n.isNS(mcNamespace, "Fallback")
+}
+
+// Get all the paragraphs of the tree in document order, including the paragraphs nested in text boxes,
+// that immediately follow their anchor paragraph. Paragraphs within alternate content fallbacks are ignored,
+// unless the node itself is the fallback.
+func (n *xnode) paragraphs() []*xnode {
+	var res []*xnode
+	n.walk(func(c *xnode) bool {
+		if c.isFallback() && c != n {
+			return false
+		}
+		if c.is("p") {
+			res = append(res, c)
+		}
+		return true
+	})
+	return res
+}
+
+// Get all the elements from the main namespace with the provided local name, in document order.
+// Elements within alternate content fallbacks are ignored.
+func (n *xnode) collect(local string) (res []*xnode) {
+	n.walk(func(c *xnode) bool {
+		if c.isFallback() {
+			return false
+		}
+		if c.is(local) {
+			res = append(res, c)
+		}
+		return true
+	})
+	return res
+}